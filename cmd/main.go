@@ -1,11 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -14,25 +17,22 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/zioyero/jira-daybot/internal/clients/jira"
 	"github.com/zioyero/jira-daybot/internal/clients/slack"
+	"github.com/zioyero/jira-daybot/internal/config"
 	"github.com/zioyero/jira-daybot/internal/daybook"
+	"github.com/zioyero/jira-daybot/internal/publish"
+	"github.com/zioyero/jira-daybot/internal/store"
 )
 
 var (
-	runNowFlag = flag.Bool("run-now", false, "Run the jobs immediately upon starting")
-	outputFlag = flag.String("output", "stdout", "Output destination")
+	runNowFlag  = flag.Bool("run-now", false, "Run the jobs immediately upon starting")
+	outputFlag  = flag.String("output", "stdout", "Output destination: slack, stdout, markdown, html or json")
+	outDirFlag  = flag.String("output-dir", "daybooks", "Directory the markdown and html outputs write a file per user per day to")
+	outFileFlag = flag.String("output-file", "", "File the json output appends to (default stdout), or the markdown and html outputs append to instead of -output-dir")
+	configFlag  = flag.String("config", "", "Path to the config file declaring users and schedules (default $DAYBOOK_CONFIG or config.yaml)")
+	replayFlag  = flag.Bool("replay-dead-letters", false, "Resend the daybooks that permanently failed to send, then exit")
+	dateFlag    = flag.String("date", "", "Send every user's daybook for a past date (YYYY-MM-DD), reconstructed from Jira history, then exit")
 )
 
-const (
-	teamPublishingEngChannel = "C04DTBQRVUK"
-	engBackendChannel        = "C032E009U2C"
-	devNullChannel           = "C07KPQHT7L7"
-)
-
-var users = []*daybook.User{
-	{AtlassianID: "61843ea1892c420072fdd376", SlackHandle: "acastillejos", SlackID: "U02L4NL51B6", DaybookChannels: []string{devNullChannel}},
-	// {AtlassianID: "630510117cfac1bfa6f9e0fb", SlackHandle: "jacob", SlackID: "U03SQC6F7L7", DaybookChannels: []string{teamPublishingEngChannel}},
-}
-
 func main() {
 	flag.Parse()
 
@@ -41,36 +41,83 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
-	color.White("Starting JIRA Daybook Deamon")
-	if *runNowFlag {
-		color.Yellow("Running jobs immediately")
+	configPath := *configFlag
+	if configPath == "" {
+		configPath = envOrDefault("DAYBOOK_CONFIG", "config.yaml")
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	d := build()
-	s, jobs := scheduleJobs(ctx, d)
+	users, err := cfg.DaybookUsers()
+	if err != nil {
+		log.Fatalf("Error loading users: %v", err)
+	}
+
+	teams, err := cfg.DaybookTeams(users)
+	if err != nil {
+		log.Fatalf("Error loading teams: %v", err)
+	}
+
+	d, history := build(cfg)
+
+	// The history store is closed before exiting, which deferring it would skip on errors
+	err = run(ctx, d, configPath, newRoster(cfg, users, teams))
+	history.Close()
+	if err != nil {
+		color.Red("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// run replays the dead letters or sends the daybooks of a past date if asked to by the flags, and
+// runs the daemon until the context is canceled otherwise.
+func run(ctx context.Context, d *daybook.Service, configPath string, r *roster) error {
+	if *replayFlag {
+		return replayDeadLetters(ctx, d, r.Users())
+	}
+
+	if *dateFlag != "" {
+		return sendPastDaybooks(ctx, d, r.Users(), *dateFlag)
+	}
+
+	color.White("Starting JIRA Daybook Deamon")
+	if *runNowFlag {
+		color.Yellow("Running jobs immediately")
+	}
+
+	s, jobs, err := scheduleJobs(ctx, d, r)
+	if err != nil {
+		return err
+	}
 
 	s.Start()
 
 	color.White("JIRA Daybook Daemon started")
 
-	color.White("Configured users: %s", users)
+	color.White("Configured users: %s", r.Users())
+
+	rl := &reloader{ctx: ctx, service: d, path: configPath, roster: r, scheduler: s, jobs: jobs}
+	go rl.watch()
 
 	for _, j := range jobs {
 		nextRun, err := j.NextRun()
 		if err != nil {
-			color.Red("Error getting next run for job %s: %v", j.Name(), err)
-			os.Exit(1)
+			s.Shutdown()
+			return fmt.Errorf("getting next run for job %s: %w", j.Name(), err)
 		}
 		color.White("Scheduled job %s will run next at %v, %s from now", j.Name(), nextRun, nextRun.Sub(time.Now()))
 
 		if *runNowFlag {
 			err = j.RunNow()
 			if err != nil {
-				color.Red("Error running job %s: %v", j.Name(), err)
-				os.Exit(1)
+				s.Shutdown()
+				return fmt.Errorf("running job %s: %w", j.Name(), err)
 			}
 		}
 	}
@@ -79,27 +126,51 @@ func main() {
 
 	color.Yellow("Shutting down JIRA Daybook Daemon")
 
-	s.Shutdown()
+	return s.Shutdown()
 }
 
-func build() *daybook.Service {
+// build wires up the service. The returned history store is closed by the caller on shutdown.
+func build(cfg *config.Config) (*daybook.Service, *store.SQLite) {
+	statuses := cfg.StatusModel()
+
 	jiraTasks, err := jira.NewClient(jira.Config{
-		JiraInstance: os.Getenv("JIRA_INSTANCE"),
-		APIToken:     os.Getenv("JIRA_TOKEN"),
-		Username:     os.Getenv("JIRA_USER"),
-		Project:      os.Getenv("JIRA_PROJECT"),
+		JiraInstance:    cfg.Jira.Instance,
+		APIToken:        os.Getenv("JIRA_TOKEN"),
+		Username:        envOrDefault("JIRA_USER", cfg.Jira.Username),
+		Projects:        cfg.Jira.AllProjects(),
+		Query:           cfg.Jira.Query,
+		MaxResults:      cfg.Jira.MaxResults,
+		Statuses:        statuses,
+		HierarchyLevels: cfg.Jira.HierarchyLevels,
 	})
 	if err != nil {
 		color.Red("Error creating JIRA client: %v", err)
 		os.Exit(1)
 	}
 
+	textTemplate, err := cfg.Templates.TextTemplate(statuses)
+	if err != nil {
+		color.Red("Error loading text template: %v", err)
+		os.Exit(1)
+	}
+
+	slackTemplate, err := cfg.Templates.SlackTemplate(statuses)
+	if err == nil && slackTemplate != nil {
+		err = slack.CheckTemplate(slackTemplate)
+	}
+	if err != nil {
+		color.Red("Error loading slack template: %v", err)
+		os.Exit(1)
+	}
+
 	// Output options
 	slackClient := slack.NewClient(&slack.Config{
-		Token:          os.Getenv("SLACK_TOKEN"),
-		DaybookChannel: os.Getenv("DAYBOOK_CHANNEL"),
+		Token:    os.Getenv("SLACK_TOKEN"),
+		Statuses: statuses,
+		Sent:     store.NewSentMessageFile(cfg.Delivery.SentFile, cfg.Delivery.SentRetention),
+		Template: slackTemplate,
 	})
-	stdoutNotifier := &daybook.StdoutNotifier{}
+	stdoutNotifier := &daybook.StdoutNotifier{Statuses: statuses, Template: textTemplate}
 
 	var output daybook.Notifier
 	switch *outputFlag {
@@ -107,52 +178,257 @@ func build() *daybook.Service {
 		output = slackClient
 	case "stdout":
 		output = stdoutNotifier
+	case "markdown":
+		output = fileNotifier(publish.Markdown{}, statuses)
+	case "html":
+		output = fileNotifier(publish.HTML{}, statuses)
+	case "json":
+		// Keep stdout for the documents, printing progress to stderr instead
+		if *outFileFlag == "" {
+			color.Output = color.Error
+		}
+
+		output = publish.NewJSONNotifier(*outFileFlag, statuses)
 	default:
 		color.Red("Invalid output flag: %s", *outputFlag)
 		os.Exit(1)
 	}
 
-	cfg := daybook.Config{}
+	retry := cfg.Delivery.RetryPolicy()
+
+	// Parent stories and epics are shared between users and looked up by both the reminder and
+	// entry jobs, so cache them across runs
+	tasks := daybook.NewCachedTaskRepository(daybook.NewRetryingTaskRepository(jiraTasks, retry), cfg.Jira.CacheTTL)
+
+	deadLetters := store.NewDeadLetterFile(cfg.Delivery.DeadLetterFile)
+
+	history, err := store.OpenSQLite(cfg.History.Database)
+	if err != nil {
+		color.Red("Error opening daybook history: %v", err)
+		os.Exit(1)
+	}
+
+	daybook := daybook.NewService(daybook.Config{
+		Statuses:    statuses,
+		Concurrency: cfg.Delivery.Concurrency,
+		UserTimeout: cfg.Delivery.UserTimeout,
+		Output:      *outputFlag,
+	}, daybook.NewRetryingNotifier(output, retry), tasks, deadLetters, history)
+
+	return daybook, history
+}
+
+func replayDeadLetters(ctx context.Context, d *daybook.Service, users []*daybook.User) error {
+	color.White("Replaying dead letters")
+
+	result, err := d.ReplayDeadLetters(ctx, users)
+	if err != nil {
+		return fmt.Errorf("replaying dead letters: %w", err)
+	}
+
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to replay %d of %d dead letters: %w", len(result.Failed()), len(result.Results), err)
+	}
+
+	color.Green("Replayed %d dead letters", len(result.Results))
 
-	daybook := daybook.NewService(cfg, output, jiraTasks)
+	return nil
+}
+
+func sendPastDaybooks(ctx context.Context, d *daybook.Service, users []*daybook.User, day string) error {
+	date, err := time.Parse(daybook.DayFormat, day)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD: %w", day, err)
+	}
+
+	// The date is in each user's time zone, so only reject dates that are in the future everywhere
+	if date.After(time.Now().Add(14 * time.Hour)) {
+		return fmt.Errorf("invalid date %s: it is in the future", day)
+	}
+
+	color.White("Sending daybooks for %s", day)
+
+	result := d.SendDaybookEntriesOn(ctx, users, date)
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("failed to send %d of %d daybooks: %w", len(result.Failed()), len(result.Results), err)
+	}
+
+	color.Green("Sent %d daybooks for %s", len(result.Results), day)
 
-	return daybook
+	return nil
 }
 
-func scheduleJobs(ctx context.Context, d *daybook.Service) (gocron.Scheduler, []gocron.Job) {
-	location, err := time.LoadLocation("America/Los_Angeles")
+func scheduleJobs(ctx context.Context, d *daybook.Service, r *roster) (gocron.Scheduler, []gocron.Job, error) {
+	s, err := gocron.NewScheduler()
 	if err != nil {
-		log.Fatalf("Error loading location: %v", err)
+		return nil, nil, fmt.Errorf("creating scheduler: %w", err)
 	}
 
-	s, err := gocron.NewScheduler(gocron.WithLocation(location))
+	jobs, err := createJobs(s, jobDefinitions(ctx, d, r.Config().Schedule, r.Users(), r.Teams()))
 	if err != nil {
-		log.Fatalf("Error creating scheduler: %v", err)
+		return nil, nil, fmt.Errorf("creating jobs: %w", err)
+	}
+
+	return s, jobs, nil
+}
+
+type jobDefinition struct {
+	name    string
+	crontab string
+	users   []*daybook.User
+	task    gocron.Task
+}
+
+// jobDefinitions describes the scheduled jobs for the given users and teams. Users are grouped into
+// one job per time zone and crontab, so that everyone gets their daybook at the same local time.
+// Each team gets a job of its own.
+func jobDefinitions(ctx context.Context, d *daybook.Service, schedule config.Schedule, users []*daybook.User, teams []*daybook.Team) []jobDefinition {
+	defs := make([]jobDefinition, 0)
+
+	// Teams without a crontab of their own post their digest right after their members' daybooks
+	// are sent, since the digest rolls up the daybooks sent that day
+	following := make([]*daybook.Team, 0)
+	scheduled := make([]*daybook.Team, 0)
+	for _, team := range teams {
+		if cmp.Or(team.Crontab, schedule.Team) == "" {
+			following = append(following, team)
+		} else {
+			scheduled = append(scheduled, team)
+		}
+	}
+
+	// Send daybook entries on the configured schedule
+	defs = append(defs, groupJobs(ctx, "SendDaybookEntry", users, func(u *daybook.User) string {
+		return cmp.Or(u.DaybookCrontab, schedule.Daybook)
+	}, func(ctx context.Context, users []*daybook.User) *daybook.BatchResult {
+		result := d.SendDaybookEntries(ctx, users)
+		sendFollowingDigests(ctx, d, following, users)
+		return result
+	})...)
+
+	// Send daybook reminder DMs on the configured schedule
+	defs = append(defs, groupJobs(ctx, "SendDaybookDMReminder", users, func(u *daybook.User) string {
+		return cmp.Or(u.ReminderCrontab, schedule.Reminder)
+	}, d.SendDaybookDMReminders)...)
+
+	// Send weekly summaries on the configured schedule
+	defs = append(defs, groupJobs(ctx, "SendWeeklySummary", users, func(u *daybook.User) string {
+		return cmp.Or(u.WeeklyCrontab, schedule.Weekly)
+	}, d.SendWeeklySummaries)...)
+
+	// Send team digests on the configured schedule
+	for _, team := range scheduled {
+		defs = append(defs, teamJob(ctx, d, team, cmp.Or(team.Crontab, schedule.Team)))
+	}
+
+	return defs
+}
+
+func groupJobs(ctx context.Context, name string, users []*daybook.User, crontab func(*daybook.User) string, send func(context.Context, []*daybook.User) *daybook.BatchResult) []jobDefinition {
+	byCrontab := make(map[string]*jobDefinition)
+	order := make([]string, 0)
+	for _, user := range users {
+		// Pin the crontab to the user's time zone
+		tab := fmt.Sprintf("CRON_TZ=%s %s", user.Location(), crontab(user))
+
+		if _, ok := byCrontab[tab]; !ok {
+			byCrontab[tab] = &jobDefinition{name: fmt.Sprintf("%s[%s]", name, tab), crontab: tab}
+			order = append(order, tab)
+		}
+
+		byCrontab[tab].users = append(byCrontab[tab].users, user)
+	}
+
+	defs := make([]jobDefinition, 0, len(order))
+	for _, tab := range order {
+		def := byCrontab[tab]
+		// Failures are reported per user by the service, and never stop the daemon, so that one
+		// user's Jira or Slack problem doesn't cost everyone else their next daybook
+		def.task = gocron.NewTask(func() {
+			result := send(ctx, def.users)
+			if err := result.Err(); err != nil {
+				color.Red("Job %s failed for %d of %d users: %v", def.name, len(result.Failed()), len(result.Results), err)
+			}
+		})
+
+		defs = append(defs, *def)
 	}
 
-	// Send daybook entry every weekday at 4:30 PM
-	entryJob, err := s.NewJob(gocron.CronJob(os.Getenv("DAYBOOK_CRONTAB"), false), gocron.NewTask(func() {
-		err = d.SendDaybookEntries(ctx, users)
+	return defs
+}
+
+// sendFollowingDigests sends the digests of the teams with a member among the users whose daybooks
+// were just sent. A team whose members are sent at different times has its digest updated each time.
+func sendFollowingDigests(ctx context.Context, d *daybook.Service, teams []*daybook.Team, users []*daybook.User) {
+	for _, team := range teams {
+		if !slices.ContainsFunc(team.Members, func(m *daybook.User) bool { return slices.Contains(users, m) }) {
+			continue
+		}
+
+		err := d.SendTeamDigest(ctx, team)
 		if err != nil {
-			color.Red("Error sending daybook entry: %v", err)
-			os.Exit(1)
+			color.Red("Sending team digest for %s failed: %v", team.Name, err)
 		}
-	}), gocron.WithName("SendDaybookEntry"))
-	if err != nil {
-		log.Fatalf("Error creating job: %v", err)
 	}
+}
+
+func teamJob(ctx context.Context, d *daybook.Service, team *daybook.Team, crontab string) jobDefinition {
+	// Pin the crontab to the team's time zone
+	tab := fmt.Sprintf("CRON_TZ=%s %s", team.Location(), crontab)
+	name := fmt.Sprintf("SendTeamDigest[%s][%s]", team.Name, tab)
+
+	return jobDefinition{
+		name:    name,
+		crontab: tab,
+		users:   team.Members,
+		task: gocron.NewTask(func() {
+			err := d.SendTeamDigest(ctx, team)
+			if err != nil {
+				color.Red("Job %s failed: %v", name, err)
+			}
+		}),
+	}
+}
 
-	// Send daybook reminder DM every weekday at 4:00 PM
-	dmJob, err := s.NewJob(gocron.CronJob(os.Getenv("REMINDER_CRONTAB"), false), gocron.NewTask(func() {
-		err = d.SendDaybookDMReminders(ctx, users)
+// createJobs adds the jobs to the scheduler. If any job can't be created, the jobs created so far
+// are removed again and the error is returned.
+func createJobs(s gocron.Scheduler, defs []jobDefinition) ([]gocron.Job, error) {
+	jobs := make([]gocron.Job, 0, len(defs))
+	for _, def := range defs {
+		j, err := s.NewJob(gocron.CronJob(def.crontab, false), def.task, gocron.WithName(def.name))
 		if err != nil {
-			color.Red("Error sending daybook reminder: %v", err)
-			os.Exit(1)
+			removeJobs(s, jobs)
+			return nil, fmt.Errorf("creating job %s: %w", def.name, err)
 		}
-	}), gocron.WithName("SendDaybookDMReminder"))
-	if err != nil {
-		log.Fatalf("Error creating job: %v", err)
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+func removeJobs(s gocron.Scheduler, jobs []gocron.Job) {
+	for _, j := range jobs {
+		err := s.RemoveJob(j.ID())
+		if err != nil {
+			color.Red("Error removing job %s: %v", j.Name(), err)
+		}
+	}
+}
+
+// fileNotifier writes documents of the format to -output-file when it is set, or to -output-dir.
+func fileNotifier(format publish.Format, statuses *daybook.StatusModel) *publish.FileNotifier {
+	if *outFileFlag != "" {
+		return publish.NewAppendingFileNotifier(*outFileFlag, format, statuses)
+	}
+
+	return publish.NewFileNotifier(*outDirFlag, format, statuses)
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
 
-	return s, []gocron.Job{entryJob, dmJob}
+	return fallback
 }
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/zioyero/jira-daybot/internal/config"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// reloadDebounce is how long to wait after the last write to the config file before reloading it,
+// since editors tend to save files in several steps.
+const reloadDebounce = 500 * time.Millisecond
+
+type activeConfig struct {
+	cfg   *config.Config
+	users []*daybook.User
+	teams []*daybook.Team
+}
+
+// roster holds the active config and the users and teams derived from it. They're swapped together
+// on reload, so readers never see users from one config and settings from another.
+type roster struct {
+	current atomic.Pointer[activeConfig]
+}
+
+func newRoster(cfg *config.Config, users []*daybook.User, teams []*daybook.Team) *roster {
+	r := &roster{}
+	r.swap(cfg, users, teams)
+
+	return r
+}
+
+func (r *roster) Config() *config.Config {
+	return r.current.Load().cfg
+}
+
+func (r *roster) Users() []*daybook.User {
+	return r.current.Load().users
+}
+
+func (r *roster) Teams() []*daybook.Team {
+	return r.current.Load().teams
+}
+
+func (r *roster) swap(cfg *config.Config, users []*daybook.User, teams []*daybook.Team) {
+	r.current.Store(&activeConfig{cfg: cfg, users: users, teams: teams})
+}
+
+// reloader reloads the config file when it changes on disk or when the daemon receives SIGHUP.
+// A config that fails to load is rejected and the previous config keeps running.
+type reloader struct {
+	ctx       context.Context
+	service   *daybook.Service
+	path      string
+	roster    *roster
+	scheduler gocron.Scheduler
+	jobs      []gocron.Job
+}
+
+func (rl *reloader) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Watch the directory rather than the file, since editors and config management tools often
+	// replace the file instead of writing to it.
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		color.Red("Error creating config watcher, only SIGHUP will reload the config: %v", err)
+	} else {
+		defer watcher.Close()
+
+		err = watcher.Add(filepath.Dir(rl.path))
+		if err != nil {
+			color.Red("Error watching config file, only SIGHUP will reload the config: %v", err)
+		}
+	}
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if watcher != nil {
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+
+	debounce := time.NewTimer(0)
+	<-debounce.C
+
+	for {
+		select {
+		case <-rl.ctx.Done():
+			return
+		case <-hup:
+			color.Yellow("Received SIGHUP, reloading config")
+			rl.reload()
+		case event := <-events:
+			if filepath.Clean(event.Name) != filepath.Clean(rl.path) {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			debounce.Reset(reloadDebounce)
+		case <-debounce.C:
+			color.Yellow("Config file %s changed, reloading config", rl.path)
+			rl.reload()
+		case err := <-errs:
+			color.Red("Error watching config file: %v", err)
+		}
+	}
+}
+
+func (rl *reloader) reload() {
+	cfg, err := config.Load(rl.path)
+	if err != nil {
+		color.Red("Rejected config reload, keeping the previous config: %v", err)
+		return
+	}
+
+	users, err := cfg.DaybookUsers()
+	if err != nil {
+		color.Red("Rejected config reload, keeping the previous config: %v", err)
+		return
+	}
+
+	teams, err := cfg.DaybookTeams(users)
+	if err != nil {
+		color.Red("Rejected config reload, keeping the previous config: %v", err)
+		return
+	}
+
+	previous := rl.roster.Config()
+	if !reflect.DeepEqual(cfg.Jira, previous.Jira) {
+		color.Yellow("Jira settings changed, restart the daemon to apply them")
+	}
+	if !reflect.DeepEqual(cfg.Statuses, previous.Statuses) {
+		color.Yellow("Status sections changed, restart the daemon to apply them")
+	}
+	if !reflect.DeepEqual(cfg.Templates, previous.Templates) {
+		color.Yellow("Templates changed, restart the daemon to apply them")
+	}
+	if !reflect.DeepEqual(cfg.Delivery, previous.Delivery) {
+		color.Yellow("Delivery settings changed, restart the daemon to apply them")
+	}
+	if !reflect.DeepEqual(cfg.History, previous.History) {
+		color.Yellow("History settings changed, restart the daemon to apply them")
+	}
+
+	// Jobs are grouped by the users' time zones and schedules, so they're recreated for the new
+	// roster. The new jobs are created before the old ones are removed, so a failure leaves the
+	// previous jobs in place.
+	jobs, err := createJobs(rl.scheduler, jobDefinitions(rl.ctx, rl.service, cfg.Schedule, users, teams))
+	if err != nil {
+		color.Red("Rejected config reload, keeping the previous config: %v", err)
+		return
+	}
+
+	removeJobs(rl.scheduler, rl.jobs)
+	rl.jobs = jobs
+	rl.roster.swap(cfg, users, teams)
+
+	color.Green("Reloaded config, %d users and %d teams configured", len(users), len(teams))
+
+	for _, j := range rl.jobs {
+		nextRun, err := j.NextRun()
+		if err != nil {
+			color.Red("Error getting next run for job %s: %v", j.Name(), err)
+			continue
+		}
+		color.White("Scheduled job %s will run next at %v", j.Name(), nextRun)
+	}
+}
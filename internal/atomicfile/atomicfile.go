@@ -0,0 +1,43 @@
+// Package atomicfile replaces files atomically, so that readers never see a partly written file and
+// a crash mid-write never loses the previous contents.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path with the data, giving it the permissions perm. The data is written
+// to a temporary file in the same directory, which is then renamed over the file.
+func Write(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	// Temporary files are only readable by their owner
+	err = os.Chmod(tmp.Name(), perm)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+
+	return nil
+}
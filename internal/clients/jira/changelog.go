@@ -0,0 +1,220 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// statusCategories maps Jira status IDs and names to their status category. Changelogs only name
+// the statuses a task moved between, so the categories are looked up separately.
+type statusCategories struct {
+	byID   map[string]string
+	byName map[string]string
+}
+
+func (s *statusCategories) category(id, name string) string {
+	if category, ok := s.byID[id]; ok {
+		return category
+	}
+
+	return s.byName[name]
+}
+
+// names returns the names of the statuses in the category, sorted.
+func (s *statusCategories) names(category string) []string {
+	names := make([]string, 0)
+	for name, c := range s.byName {
+		if c == category {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// statusCategories returns the category of every status in the Jira instance. They're fetched once
+// and kept for the lifetime of the client, since changing the workflow requires a restart anyway.
+func (c *Client) statusCategories(ctx context.Context) (*statusCategories, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.categories != nil {
+		return c.categories, nil
+	}
+
+	statuses, resp, err := c.jira.Status.GetAllStatuses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting statuses: %w", wrapError(resp, err))
+	}
+
+	categories := &statusCategories{byID: make(map[string]string), byName: make(map[string]string)}
+	for _, status := range statuses {
+		categories.byID[status.ID] = status.StatusCategory.Name
+		categories.byName[status.Name] = status.StatusCategory.Name
+	}
+
+	c.categories = categories
+
+	return categories, nil
+}
+
+// fieldChange is a change of one of an issue's fields, taken from its changelog. From and To are
+// the IDs of the values, such as status IDs or account IDs, alongside their display names.
+type fieldChange struct {
+	at         time.Time
+	from       string
+	fromString string
+	to         string
+	toString   string
+}
+
+// fieldChanges returns the changes of the field in the issue's changelog, oldest first.
+func fieldChanges(issue jiralib.Issue, field string) []fieldChange {
+	changes := make([]fieldChange, 0)
+	if issue.Changelog == nil {
+		return changes
+	}
+
+	for _, history := range issue.Changelog.Histories {
+		at, err := history.CreatedTime()
+		if err != nil {
+			slog.Warn("Parsing changelog time", "Issue", issue.Key, "Created", history.Created, "Error", err)
+			continue
+		}
+
+		for _, item := range history.Items {
+			if item.Field != field {
+				continue
+			}
+
+			changes = append(changes, fieldChange{
+				at:         at,
+				from:       changeValue(item.From),
+				fromString: item.FromString,
+				to:         changeValue(item.To),
+				toString:   item.ToString,
+			})
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b fieldChange) int {
+		return a.at.Compare(b.at)
+	})
+
+	return changes
+}
+
+func changeValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+// valueAt returns the ID and name of the field's value at the time, given its changes and its
+// current value.
+func valueAt(changes []fieldChange, at time.Time, currentID, current string) (string, string) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		if !changes[i].at.After(at) {
+			return changes[i].to, changes[i].toString
+		}
+	}
+
+	// The field hasn't changed since, or only changed afterwards
+	if len(changes) > 0 {
+		return changes[0].from, changes[0].fromString
+	}
+
+	return currentID, current
+}
+
+// statusTransition is a change of an issue's status between status categories.
+type statusTransition struct {
+	at           time.Time
+	fromCategory string
+	toCategory   string
+}
+
+func statusTransitions(changes []fieldChange, categories *statusCategories) []statusTransition {
+	transitions := make([]statusTransition, 0, len(changes))
+	for _, change := range changes {
+		transitions = append(transitions, statusTransition{
+			at:           change.at,
+			fromCategory: categories.category(change.from, change.fromString),
+			toCategory:   categories.category(change.to, change.toString),
+		})
+	}
+
+	return transitions
+}
+
+// setTransitionTimes sets when the task was last started and completed from the status changes in
+// its changelog. A task that was created in its current category, without a transition into it,
+// counts from when it was created.
+func setTransitionTimes(task *daybook.Task, created time.Time, changes []fieldChange, categories *statusCategories) {
+	transitions := statusTransitions(changes, categories)
+
+	if task.StatusCategory == daybook.CategoryInProgress || task.StatusCategory == daybook.CategoryDone {
+		task.StartedAt = lastTransition(transitions, created, func(t statusTransition) bool {
+			return t.fromCategory == daybook.CategoryToDo && t.toCategory != daybook.CategoryToDo
+		})
+	}
+
+	if task.StatusCategory == daybook.CategoryDone {
+		task.CompletedAt = lastTransition(transitions, created, func(t statusTransition) bool {
+			return t.fromCategory != daybook.CategoryDone && t.toCategory == daybook.CategoryDone
+		})
+	}
+}
+
+// lastTransition returns the time of the latest transition matching the predicate, or the fallback
+// if none does.
+func lastTransition(transitions []statusTransition, fallback time.Time, match func(statusTransition) bool) time.Time {
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if match(transitions[i]) {
+			return transitions[i].at
+		}
+	}
+
+	return fallback
+}
+
+// restoreTask sets the task's status and transition times to what they were at the time, using the
+// issue's changelog. It returns false if the task wasn't assigned to the account at the time.
+func restoreTask(task *daybook.Task, issue jiralib.Issue, accountID string, at time.Time, categories *statusCategories) bool {
+	assignee := ""
+	if issue.Fields.Assignee != nil {
+		assignee = issue.Fields.Assignee.AccountID
+	}
+
+	assignee, _ = valueAt(fieldChanges(issue, "assignee"), at, assignee, "")
+	if assignee != accountID {
+		return false
+	}
+
+	changes := fieldChanges(issue, "status")
+
+	statusID, status := valueAt(changes, at, issue.Fields.Status.ID, issue.Fields.Status.Name)
+	task.Status = status
+	task.StatusCategory = categories.category(statusID, status)
+
+	// Later transitions hadn't happened yet
+	past := make([]fieldChange, 0, len(changes))
+	for _, change := range changes {
+		if !change.at.After(at) {
+			past = append(past, change)
+		}
+	}
+
+	setTransitionTimes(task, time.Time(issue.Fields.Created), past, categories)
+
+	return true
+}
@@ -0,0 +1,244 @@
+package jira
+
+import (
+	"testing"
+	"time"
+
+	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+var testCategories = &statusCategories{
+	byID: map[string]string{
+		"1": daybook.CategoryToDo,
+		"3": daybook.CategoryInProgress,
+		"4": daybook.CategoryInProgress,
+		"5": daybook.CategoryDone,
+	},
+	byName: map[string]string{
+		"To Do":       daybook.CategoryToDo,
+		"In Progress": daybook.CategoryInProgress,
+		"In Review":   daybook.CategoryInProgress,
+		"Done":        daybook.CategoryDone,
+	},
+}
+
+func at(day, hour int) time.Time {
+	return time.Date(2024, time.June, day, hour, 0, 0, 0, time.UTC)
+}
+
+// statusChange returns a change between the statuses in testCategories, named by their IDs.
+func statusChange(when time.Time, from, to string) fieldChange {
+	names := map[string]string{"1": "To Do", "3": "In Progress", "4": "In Review", "5": "Done"}
+
+	return fieldChange{at: when, from: from, fromString: names[from], to: to, toString: names[to]}
+}
+
+func TestSetTransitionTimes(t *testing.T) {
+	created := at(1, 9)
+
+	tests := []struct {
+		name          string
+		category      string
+		changes       []fieldChange
+		wantStarted   time.Time
+		wantCompleted time.Time
+	}{
+		{
+			name:     "to do",
+			category: daybook.CategoryToDo,
+			changes:  []fieldChange{},
+		},
+		{
+			name:        "created in progress",
+			category:    daybook.CategoryInProgress,
+			changes:     []fieldChange{},
+			wantStarted: created,
+		},
+		{
+			name:          "created done",
+			category:      daybook.CategoryDone,
+			changes:       []fieldChange{},
+			wantStarted:   created,
+			wantCompleted: created,
+		},
+		{
+			name:     "started",
+			category: daybook.CategoryInProgress,
+			changes: []fieldChange{
+				statusChange(at(2, 10), "1", "3"),
+				statusChange(at(2, 15), "3", "4"),
+			},
+			wantStarted: at(2, 10),
+		},
+		{
+			name:     "stopped and started again",
+			category: daybook.CategoryInProgress,
+			changes: []fieldChange{
+				statusChange(at(2, 10), "1", "3"),
+				statusChange(at(3, 10), "3", "1"),
+				statusChange(at(4, 10), "1", "3"),
+			},
+			wantStarted: at(4, 10),
+		},
+		{
+			name:     "reopened and completed again",
+			category: daybook.CategoryDone,
+			changes: []fieldChange{
+				statusChange(at(2, 10), "1", "3"),
+				statusChange(at(3, 10), "3", "5"),
+				statusChange(at(4, 10), "5", "4"),
+				statusChange(at(5, 10), "4", "5"),
+			},
+			wantStarted:   at(2, 10),
+			wantCompleted: at(5, 10),
+		},
+		{
+			name:     "statuses known by name only",
+			category: daybook.CategoryDone,
+			changes: []fieldChange{
+				{at: at(2, 10), from: "10", fromString: "To Do", to: "11", toString: "In Progress"},
+				{at: at(3, 10), from: "11", fromString: "In Progress", to: "12", toString: "Done"},
+			},
+			wantStarted:   at(2, 10),
+			wantCompleted: at(3, 10),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &daybook.Task{StatusCategory: tt.category}
+
+			setTransitionTimes(task, created, tt.changes, testCategories)
+
+			if !task.StartedAt.Equal(tt.wantStarted) {
+				t.Errorf("StartedAt = %v, want %v", task.StartedAt, tt.wantStarted)
+			}
+			if !task.CompletedAt.Equal(tt.wantCompleted) {
+				t.Errorf("CompletedAt = %v, want %v", task.CompletedAt, tt.wantCompleted)
+			}
+		})
+	}
+}
+
+// history returns a changelog entry changing the field, as Jira reports it.
+func history(when time.Time, field, from, fromString, to, toString string) jiralib.ChangelogHistory {
+	return jiralib.ChangelogHistory{
+		Created: when.Format("2006-01-02T15:04:05.000-0700"),
+		Items: []jiralib.ChangelogItems{
+			{Field: field, From: from, FromString: fromString, To: to, ToString: toString},
+		},
+	}
+}
+
+func TestRestoreTask(t *testing.T) {
+	// Created on the 1st, started on the 2nd, completed on the 3rd and handed over on the 4th. The
+	// changelog is newest first, as Jira returns it.
+	issue := jiralib.Issue{
+		Key: "DAY-1",
+		Fields: &jiralib.IssueFields{
+			Status:   &jiralib.Status{ID: "5", Name: "Done"},
+			Assignee: &jiralib.User{AccountID: "bob"},
+			Created:  jiralib.Time(at(1, 9)),
+		},
+		Changelog: &jiralib.Changelog{
+			Histories: []jiralib.ChangelogHistory{
+				history(at(4, 9), "assignee", "alice", "Alice", "bob", "Bob"),
+				history(at(3, 15), "status", "3", "In Progress", "5", "Done"),
+				history(at(2, 10), "status", "1", "To Do", "3", "In Progress"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		accountID     string
+		at            time.Time
+		wantOK        bool
+		wantStatus    string
+		wantCategory  string
+		wantStarted   time.Time
+		wantCompleted time.Time
+	}{
+		{
+			name:         "before the changes",
+			accountID:    "alice",
+			at:           at(1, 17),
+			wantOK:       true,
+			wantStatus:   "To Do",
+			wantCategory: daybook.CategoryToDo,
+		},
+		{
+			name:         "at a change",
+			accountID:    "alice",
+			at:           at(2, 10),
+			wantOK:       true,
+			wantStatus:   "In Progress",
+			wantCategory: daybook.CategoryInProgress,
+			wantStarted:  at(2, 10),
+		},
+		{
+			name:         "between the changes",
+			accountID:    "alice",
+			at:           at(2, 17),
+			wantOK:       true,
+			wantStatus:   "In Progress",
+			wantCategory: daybook.CategoryInProgress,
+			wantStarted:  at(2, 10),
+		},
+		{
+			name:          "after the status changes",
+			accountID:     "alice",
+			at:            at(3, 17),
+			wantOK:        true,
+			wantStatus:    "Done",
+			wantCategory:  daybook.CategoryDone,
+			wantStarted:   at(2, 10),
+			wantCompleted: at(3, 15),
+		},
+		{
+			name:      "assigned to someone else later",
+			accountID: "bob",
+			at:        at(3, 17),
+		},
+		{
+			name:      "no longer assigned",
+			accountID: "alice",
+			at:        at(4, 17),
+		},
+		{
+			name:          "after all changes",
+			accountID:     "bob",
+			at:            at(4, 17),
+			wantOK:        true,
+			wantStatus:    "Done",
+			wantCategory:  daybook.CategoryDone,
+			wantStarted:   at(2, 10),
+			wantCompleted: at(3, 15),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &daybook.Task{ID: issue.Key, Status: "Done", StatusCategory: daybook.CategoryDone}
+
+			ok := restoreTask(task, issue, tt.accountID, tt.at, testCategories)
+			if ok != tt.wantOK {
+				t.Fatalf("restoreTask() = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+
+			if task.Status != tt.wantStatus || task.StatusCategory != tt.wantCategory {
+				t.Errorf("status = %q (%s), want %q (%s)", task.Status, task.StatusCategory, tt.wantStatus, tt.wantCategory)
+			}
+			if !task.StartedAt.Equal(tt.wantStarted) {
+				t.Errorf("StartedAt = %v, want %v", task.StartedAt, tt.wantStarted)
+			}
+			if !task.CompletedAt.Equal(tt.wantCompleted) {
+				t.Errorf("CompletedAt = %v, want %v", task.CompletedAt, tt.wantCompleted)
+			}
+		})
+	}
+}
@@ -2,23 +2,71 @@ package jira
 
 import (
 	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
 
 	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
 type Config struct {
 	JiraInstance string
 	Username     string
 	APIToken     string
-	Project      string
+	// Projects are the keys of the projects to report on.
+	Projects []string
+	// Query is an optional text/template for the JQL query of a user's tasks, replacing the default
+	// query. See queryData for the available placeholders.
+	Query string
+	// MaxResults is the maximum number of issues fetched for a single search, across all pages.
+	// Defaults to DefaultMaxResults.
+	MaxResults int
+	// Statuses determines which statuses are queried. DefaultStatusModel is used when nil.
+	Statuses *daybook.StatusModel
+	// HierarchyLevels maps issue type names to their level in the Jira issue hierarchy, overriding
+	// the levels Jira reports for the issue types. Optional.
+	HierarchyLevels map[string]int
 }
 
+// DefaultMaxResults is the default maximum number of issues fetched for a single search.
+const DefaultMaxResults = 500
+
 type Client struct {
-	cfg  Config
-	jira *jiralib.Client
+	cfg   Config
+	jira  *jiralib.Client
+	query *template.Template
+
+	mu         sync.Mutex
+	categories *statusCategories
+	// levels are the hierarchy levels of the issue types, keyed by issue type ID
+	levels map[string]int
 }
 
 func NewClient(cfg Config) (*Client, error) {
+	if cfg.Statuses == nil {
+		cfg.Statuses = daybook.DefaultStatusModel()
+	}
+
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = DefaultMaxResults
+	}
+
+	if len(cfg.Projects) == 0 && cfg.Query == "" {
+		return nil, fmt.Errorf("at least one project or a query template is required")
+	}
+
+	if cfg.Query == "" {
+		cfg.Query = defaultQuery
+	}
+
+	query, err := template.New("query").Option("missingkey=error").Parse(cfg.Query)
+	if err != nil {
+		return nil, fmt.Errorf("parsing query template: %w", err)
+	}
+
 	tp := jiralib.BasicAuthTransport{
 		Username: cfg.Username,
 		APIToken: cfg.APIToken,
@@ -30,7 +78,27 @@ func NewClient(cfg Config) (*Client, error) {
 	}
 
 	return &Client{
-		cfg:  cfg,
-		jira: client,
+		cfg:   cfg,
+		jira:  client,
+		query: query,
 	}, nil
 }
+
+// wrapError marks rate limits and server errors from Jira as retryable, honoring the Retry-After
+// header when Jira sends one.
+func wrapError(resp *jiralib.Response, err error) error {
+	if err == nil || resp == nil || resp.Response == nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return err
+	}
+
+	retryAfter := time.Duration(0)
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return &daybook.RetryableError{Err: err, RetryAfter: retryAfter}
+}
@@ -4,66 +4,154 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
+
+	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
 
 	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
-// UserTasks returns all tasks assigned to the user that are in progress or in code review, as well
-// as tasks that have marked as done in the last 24 hours, in order to populate the daybook.
-func (c *Client) UserTasks(ctx context.Context, user *daybook.User) ([]*daybook.Task, error) {
+// UserTasks returns all tasks assigned to the user that are reported by the status model, such as
+// tasks in progress or in code review, as well as tasks in recent sections that were updated
+// within the window, such as tasks marked as done, in order to populate the daybook. The tasks'
+// changelogs are fetched along with them, so that the daybook can tell when they were actually
+// started and completed.
+func (c *Client) UserTasks(ctx context.Context, user *daybook.User, window daybook.Window) (*daybook.SearchResult, error) {
 	slog.Info("Getting user tasks")
 
-	query := fmt.Sprintf("project = %s AND type != EPIC AND (assignee IN (%q)) AND ((status IN (\"In Progress\", \"Code Review\", \"Testing\")) OR (status IN (\"Done\") AND updated >= -24h) OR (status = \"To Do\" AND updated >= -24h))", c.cfg.Project, user.AtlassianID)
+	query, err := c.userTasksQuery(user, window)
+	if err != nil {
+		return nil, fmt.Errorf("building query: %w", err)
+	}
+
+	categories, err := c.statusCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, total, err := c.searchIssues(ctx, query, &jiralib.SearchOptions{Expand: "changelog"})
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := c.unmarshalTasks(ctx, issues)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, task := range tasks {
+		setTransitionTimes(task, time.Time(issues[i].Fields.Created), fieldChanges(issues[i], "status"), categories)
+	}
+
+	return &daybook.SearchResult{Tasks: tasks, Total: max(total, len(tasks))}, nil
+}
+
+// UserTasksOn returns the tasks the user had at the end of the past window, reconstructed from the
+// tasks' changelogs. The search matches every task that was assigned to the user during the window,
+// and the statuses and assignees as of the end of the window decide which of them are reported.
+// Titles and parents are reported as they are now.
+func (c *Client) UserTasksOn(ctx context.Context, user *daybook.User, window daybook.Window) (*daybook.SearchResult, error) {
+	slog.Info("Getting past user tasks", "Start", window.Start, "End", window.End)
+
+	categories, err := c.statusCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := c.issueTypeLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	issues, _, err := c.jira.Issue.Search(ctx, query, nil)
+	query := c.pastUserTasksQuery(user, window, categories)
+
+	issues, total, err := c.searchIssues(ctx, query, &jiralib.SearchOptions{Expand: "changelog"})
 	if err != nil {
-		return nil, fmt.Errorf("searching issues: %w", err)
+		return nil, err
+	}
+
+	tasks := make([]*daybook.Task, 0, len(issues))
+	for _, issue := range issues {
+		task, err := c.unmarshalTask(issue, levels)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling task: %w", err)
+		}
+
+		if !restoreTask(task, issue, user.AtlassianID, window.End, categories) {
+			continue
+		}
+
+		if _, ok := c.cfg.Statuses.Section(task); !ok {
+			continue
+		}
+
+		tasks = append(tasks, task)
 	}
 
-	return c.unmarshalTasks(issues)
+	// Issues that matched the search but weren't fetched may have been reported too
+	return &daybook.SearchResult{Tasks: tasks, Total: len(tasks) + max(total-len(issues), 0)}, nil
 }
 
 func (c *Client) Task(ctx context.Context, taskID string) (*daybook.Task, error) {
-	issue, _, err := c.jira.Issue.Get(ctx, taskID, nil)
+	issue, resp, err := c.jira.Issue.Get(ctx, taskID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("getting issue: %w", err)
+		return nil, fmt.Errorf("getting issue: %w", wrapError(resp, err))
 	}
 
 	if issue == nil {
 		return nil, fmt.Errorf("issue not found")
 	}
 
-	return c.unmarshalTask(*issue)
+	levels, err := c.issueTypeLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.unmarshalTask(*issue, levels)
 }
 
-// RootTask returns the root task for the given task ID. If the task has a parent, it will recursively
-// call itself until it finds the root task.
-func (c *Client) RootTask(ctx context.Context, taskID string) (*daybook.Task, error) {
-	slog.Info(fmt.Sprintf("Getting root task for %s", taskID))
+// tasksBatchSize is the number of keys looked up per search, keeping the JQL query reasonably short.
+const tasksBatchSize = 100
 
-	issue, _, err := c.jira.Issue.Get(ctx, taskID, nil)
-	if err != nil {
-		return nil, fmt.Errorf("getting issue: %w", err)
-	}
+// Tasks returns the tasks with the given IDs, looking them up with a "key IN (...)" search rather
+// than one request per task. Tasks that don't exist or aren't visible to the bot are left out.
+func (c *Client) Tasks(ctx context.Context, taskIDs []string) ([]*daybook.Task, error) {
+	slog.Info("Getting tasks", "Count", len(taskIDs))
+
+	// A batch larger than the maximum number of results would leave some of its tasks out
+	batchSize := min(tasksBatchSize, c.cfg.MaxResults)
 
-	if issue.Fields.Parent == nil {
-		return c.unmarshalTask(*issue)
+	tasks := make([]*daybook.Task, 0, len(taskIDs))
+	for start := 0; start < len(taskIDs); start += batchSize {
+		batch := taskIDs[start:min(start+batchSize, len(taskIDs))]
+		query := fmt.Sprintf("key IN (%s)", jqlList(batch))
+
+		// Don't fail the whole batch if one of the keys no longer exists
+		result, err := c.search(ctx, query, &jiralib.SearchOptions{ValidateQuery: "warn"})
+		if err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, result.Tasks...)
 	}
 
-	return c.RootTask(ctx, issue.Fields.Parent.Key)
+	return tasks, nil
 }
 
-// CreatedByUser returns all tasks created by the user since the beginning of the day,
-// according to the JQL startOfDay() function.
-func (c *Client) CreatedByUser(ctx context.Context) ([]*daybook.Task, error) {
+// CreatedByUser returns all tasks reported by the user within the window, such as the user's day.
+func (c *Client) CreatedByUser(ctx context.Context, user *daybook.User, window daybook.Window) (*daybook.SearchResult, error) {
 	slog.Info("Getting tasks created by user")
 
-	query := fmt.Sprintf("project = %s AND reporter = currentUser() and created >= startOfDay()", c.cfg.Project)
+	query := fmt.Sprintf("reporter = %s AND created >= %s", jqlString(user.AtlassianID), relativeTime(window.Start))
 
-	issues, _, err := c.jira.Issue.Search(ctx, query, nil)
-	if err != nil {
-		return nil, fmt.Errorf("searching issues: %w", err)
+	// Today's window ends now, and bounding it to the minute would leave out the latest tasks
+	if time.Since(window.End) > time.Minute {
+		query += fmt.Sprintf(" AND created <= %s", relativeTime(window.End))
+	}
+
+	if len(c.cfg.Projects) > 0 {
+		query = fmt.Sprintf("project IN (%s) AND %s", jqlList(c.cfg.Projects), query)
 	}
 
-	return c.unmarshalTasks(issues)
+	return c.search(ctx, query, nil)
 }
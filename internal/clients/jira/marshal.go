@@ -1,18 +1,25 @@
 package jira
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
 	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
-func (c *Client) unmarshalTasks(issues []jiralib.Issue) ([]*daybook.Task, error) {
+func (c *Client) unmarshalTasks(ctx context.Context, issues []jiralib.Issue) ([]*daybook.Task, error) {
+	levels, err := c.issueTypeLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	tasks := make([]*daybook.Task, 0, len(issues))
 
 	for _, issue := range issues {
-		t, err := c.unmarshalTask(issue)
+		t, err := c.unmarshalTask(issue, levels)
 		if err != nil {
 			return nil, fmt.Errorf("unmarshaling task: %w", err)
 		}
@@ -22,7 +29,7 @@ func (c *Client) unmarshalTasks(issues []jiralib.Issue) ([]*daybook.Task, error)
 	return tasks, nil
 }
 
-func (c *Client) unmarshalTask(issue jiralib.Issue) (*daybook.Task, error) {
+func (c *Client) unmarshalTask(issue jiralib.Issue, levels map[string]int) (*daybook.Task, error) {
 	link, err := url.Parse(c.cfg.JiraInstance + "/browse/" + issue.Key)
 	if err != nil {
 		return nil, fmt.Errorf("parsing issue link: %w", err)
@@ -35,11 +42,74 @@ func (c *Client) unmarshalTask(issue jiralib.Issue) (*daybook.Task, error) {
 	}
 
 	return &daybook.Task{
-		ID:           issue.Key,
-		Title:        issue.Fields.Summary,
-		Link:         link,
-		Status:       issue.Fields.Status.Name,
-		ParentTaskID: parentKey,
-		Type:         issue.Fields.Type.Name,
+		ID:             issue.Key,
+		Title:          issue.Fields.Summary,
+		Link:           link,
+		Status:         issue.Fields.Status.Name,
+		StatusCategory: issue.Fields.Status.StatusCategory.Name,
+		ParentTaskID:   parentKey,
+		Type:           issue.Fields.Type.Name,
+		Level:          c.hierarchyLevel(issue.Fields.Type, levels),
 	}, nil
 }
+
+// hierarchyLevel returns the level of the issue type in the Jira issue hierarchy. The configured
+// levels override the levels of the issue types in Jira, which are keyed by issue type ID. Types
+// missing from both fall back to the standard levels.
+func (c *Client) hierarchyLevel(issueType jiralib.IssueType, levels map[string]int) int {
+	if level, ok := c.cfg.HierarchyLevels[issueType.Name]; ok {
+		return level
+	}
+
+	if level, ok := levels[issueType.ID]; ok {
+		return level
+	}
+
+	switch {
+	case issueType.Subtask:
+		return daybook.LevelSubtask
+	case issueType.Name == "Epic":
+		return daybook.LevelEpic
+	default:
+		return daybook.LevelStory
+	}
+}
+
+// issueType is an issue type as returned by Jira's issue type API. The issue types of issues don't
+// include their hierarchy level, so the levels are looked up separately.
+type issueType struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	HierarchyLevel int    `json:"hierarchyLevel"`
+}
+
+// issueTypeLevels returns the hierarchy level of every issue type visible to the bot, keyed by issue
+// type ID. They're fetched once and kept for the lifetime of the client, like the status categories.
+func (c *Client) issueTypeLevels(ctx context.Context) (map[string]int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.levels != nil {
+		return c.levels, nil
+	}
+
+	req, err := c.jira.NewRequest(ctx, http.MethodGet, "rest/api/3/issuetype", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating issue types request: %w", err)
+	}
+
+	var types []issueType
+	resp, err := c.jira.Do(req, &types)
+	if err != nil {
+		return nil, fmt.Errorf("getting issue types: %w", wrapError(resp, err))
+	}
+
+	levels := make(map[string]int, len(types))
+	for _, t := range types {
+		levels[t.ID] = t.HierarchyLevel
+	}
+
+	c.levels = levels
+
+	return levels, nil
+}
@@ -0,0 +1,145 @@
+package jira
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// defaultQuery matches the user's tasks in the configured projects, leaving out epics since they're
+// reported through the tasks within them.
+const defaultQuery = `project IN ({{.Projects}}) AND type != Epic AND assignee = {{.Assignee}} AND {{.Statuses}}`
+
+// queryData holds the placeholders available to query templates. Values are already quoted JQL, so
+// they can be used in the template as-is.
+type queryData struct {
+	// Projects is the list of configured project keys, without the surrounding parentheses.
+	Projects string
+	// Assignee is the daybook user's Atlassian account ID.
+	Assignee string
+	// Since and Until are the start and end of the daybook's window, as JQL relative times.
+	Since string
+	Until string
+	// Statuses is the clause matching the statuses reported by the status model.
+	Statuses string
+}
+
+// userTasksQuery renders the query template for the user's tasks within the window.
+func (c *Client) userTasksQuery(user *daybook.User, window daybook.Window) (string, error) {
+	since := relativeTime(window.Start)
+
+	data := queryData{
+		Projects: jqlList(c.cfg.Projects),
+		Assignee: jqlString(user.AtlassianID),
+		Since:    since,
+		Until:    relativeTime(window.End),
+		Statuses: statusClause(c.cfg.Statuses, since),
+	}
+
+	sb := strings.Builder{}
+	err := c.query.Execute(&sb, data)
+	if err != nil {
+		return "", fmt.Errorf("rendering query template: %w", err)
+	}
+
+	return sb.String(), nil
+}
+
+// pastUserTasksQuery builds the query for the tasks that were assigned to the user during the past
+// window and were either in a reported status or changed status, leaving the status as of the end of
+// the window to be reconstructed from the changelog. The query template can't be used, since it
+// matches tasks as they are now.
+func (c *Client) pastUserTasksQuery(user *daybook.User, window daybook.Window, categories *statusCategories) string {
+	during := fmt.Sprintf("DURING (%s, %s)", jqlString(relativeTime(window.Start)), jqlString(relativeTime(window.End)))
+
+	clauses := make([]string, 0, 4)
+	if len(c.cfg.Projects) > 0 {
+		clauses = append(clauses, fmt.Sprintf("project IN (%s)", jqlList(c.cfg.Projects)))
+	}
+
+	clauses = append(clauses,
+		"type != Epic",
+		fmt.Sprintf("assignee WAS %s %s", jqlString(user.AtlassianID), during),
+		fmt.Sprintf("(status WAS IN (%s) %s OR status CHANGED %s)", jqlList(reportedStatuses(c.cfg.Statuses, categories)), during, during),
+	)
+
+	return strings.Join(clauses, " AND ")
+}
+
+// reportedStatuses returns the names of the statuses reported by the status model, including the
+// statuses of the categories mapped to sections.
+func reportedStatuses(statuses *daybook.StatusModel, categories *statusCategories) []string {
+	names := make([]string, 0)
+	for _, section := range statuses.Sections {
+		names = append(names, section.Statuses...)
+		if section.Category != "" {
+			names = append(names, categories.names(section.Category)...)
+		}
+	}
+
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(statuses.Excluded, name)
+	})
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+// statusClause builds the JQL clause matching the tasks reported by the status model. Tasks in
+// recent sections are only matched if they were updated since the given JQL time.
+func statusClause(statuses *daybook.StatusModel, since string) string {
+	clauses := make([]string, 0)
+	for _, section := range statuses.Sections {
+		matches := make([]string, 0, 2)
+		if len(section.Statuses) > 0 {
+			matches = append(matches, fmt.Sprintf("status IN (%s)", jqlList(section.Statuses)))
+		}
+		if section.Category != "" {
+			matches = append(matches, fmt.Sprintf("statusCategory = %s", jqlString(section.Category)))
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		clause := strings.Join(matches, " OR ")
+		if section.Recent {
+			clause = fmt.Sprintf("(%s) AND updated >= %s", clause, since)
+		}
+
+		clauses = append(clauses, "("+clause+")")
+	}
+
+	clause := "(" + strings.Join(clauses, " OR ") + ")"
+	if len(statuses.Excluded) > 0 {
+		clause += fmt.Sprintf(" AND status NOT IN (%s)", jqlList(statuses.Excluded))
+	}
+
+	return clause
+}
+
+// jqlString quotes s as a JQL string literal.
+func jqlString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+	return `"` + r.Replace(s) + `"`
+}
+
+// jqlList quotes each value and joins them into a JQL list, without the surrounding parentheses.
+func jqlList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, jqlString(v))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// relativeTime formats t as a JQL offset from now, such as "-390m". JQL interprets absolute dates in
+// the bot account's time zone, so offsets are used to keep windows in the daybook user's time zone.
+func relativeTime(t time.Time) string {
+	return fmt.Sprintf("-%dm", int(math.Ceil(time.Since(t).Minutes())))
+}
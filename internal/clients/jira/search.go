@@ -0,0 +1,64 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	jiralib "github.com/andygrunwald/go-jira/v2/cloud"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// searchPageSize is the number of issues requested per page. Jira may return fewer.
+const searchPageSize = 100
+
+// search runs the JQL query and fetches every page of results, up to the configured maximum. The
+// options, if given, are used for every page, with the paging fields overridden.
+func (c *Client) search(ctx context.Context, query string, options *jiralib.SearchOptions) (*daybook.SearchResult, error) {
+	issues, total, err := c.searchIssues(ctx, query, options)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := c.unmarshalTasks(ctx, issues)
+	if err != nil {
+		return nil, err
+	}
+
+	return &daybook.SearchResult{Tasks: tasks, Total: max(total, len(tasks))}, nil
+}
+
+// searchIssues is search without converting the issues to tasks, returning the number of issues that
+// matched the query alongside the fetched ones.
+func (c *Client) searchIssues(ctx context.Context, query string, options *jiralib.SearchOptions) ([]jiralib.Issue, int, error) {
+	if options == nil {
+		options = &jiralib.SearchOptions{}
+	}
+
+	issues := make([]jiralib.Issue, 0)
+	total := 0
+
+	for len(issues) < c.cfg.MaxResults {
+		opts := *options
+		opts.StartAt = len(issues)
+		opts.MaxResults = min(searchPageSize, c.cfg.MaxResults-len(issues))
+
+		page, resp, err := c.jira.Issue.Search(ctx, query, &opts)
+		if err != nil {
+			return nil, 0, fmt.Errorf("searching issues: %w", wrapError(resp, err))
+		}
+
+		issues = append(issues, page...)
+		total = resp.Total
+
+		if len(page) == 0 || len(issues) >= total {
+			break
+		}
+	}
+
+	if total > len(issues) {
+		slog.Warn("Search results truncated", "Query", query, "Fetched", len(issues), "Total", total)
+	}
+
+	return issues, total, nil
+}
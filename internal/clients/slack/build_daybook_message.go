@@ -1,135 +1,56 @@
 package slack
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 
-	"github.com/zioyero/go-slack"
 	slackapi "github.com/zioyero/go-slack"
 	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
-func (c *Client) buildDaybookMessage(db *daybook.Daybook) []slack.Block {
-	statusUpdates := map[string]string{
-		"In Progress": "Working on",
-		"Code Review": "In Code Review",
-		"Testing":     "Testing",
-		"Done":        "Completed Today",
+// CheckTemplate renders a sample daybook with the template, making sure that it renders a JSON
+// object with the message's blocks.
+func CheckTemplate(tmpl *daybook.DaybookTemplate) error {
+	buf := bytes.Buffer{}
+	err := tmpl.ExecuteSample(&buf)
+	if err != nil {
+		return err
 	}
 
-	order := []string{"Done", "In Progress", "Code Review", "Testing"}
+	_, err = decodeBlocks(buf.Bytes())
 
-	bugs := daybook.TasksByStatus(db.Bugs)
-
-	blocks := make([]slackapi.Block, 0)
-
-	blocks = append(blocks,
-		slackapi.NewSectionBlock(
-			slackapi.NewTextBlockObject("mrkdwn",
-				fmt.Sprintf("<@%s> *Daybook for %s*", db.User.SlackHandle, db.Day.Format("2006-01-02")), false, false,
-			),
-			nil,
-			nil,
-		),
-	)
-
-	for _, status := range order {
-		epics := db.Projects[status]
-		bb := bugs[status]
-
-		taskCount := len(epics) + len(bb)
-
-		if taskCount == 0 {
-			continue
-		}
-
-		blocks = append(blocks,
-			slackapi.NewSectionBlock(
-				slackapi.NewTextBlockObject("mrkdwn",
-					fmt.Sprintf("*%s*", statusUpdates[status]), false, false,
-				),
-				nil,
-				nil,
-			),
-		)
-
-		for _, bug := range bb {
-			blocks = append(blocks, c.formatBugReport(bug, 0))
-		}
-
-		for _, epic := range epics {
-			blocks = append(blocks, c.formatEpicReport(epic, 0))
-		}
-	}
-
-	return blocks
+	return err
 }
 
-func (c *Client) formatBugReport(bug *daybook.Task, indent int) slackapi.Block {
-	return slackapi.NewRichTextBlock("",
-		slackapi.NewRichTextList(slackapi.RTEListBullet, indent,
-			slackapi.NewRichTextSection(
-				slackapi.NewRichTextSectionEmojiElement("jira-bug", 0, nil),
-				slackapi.NewRichTextSectionLinkElement(bug.Link.String(), " "+bug.Title, nil),
-			),
-		),
-	)
-}
-
-func (c *Client) formatEpicReport(epic *daybook.Epic, indent int) *slackapi.RichTextBlock {
-	listHeader := []slackapi.RichTextSectionElement{
-		slackapi.NewRichTextSectionEmojiElement("jira-epic", 0, nil),
-		slackapi.NewRichTextSectionLinkElement(epic.Link.String(), " "+epic.Title, nil),
+func (c *Client) buildDaybookMessage(db *daybook.Daybook) ([]slackapi.Block, error) {
+	if c.config.Template == nil {
+		return c.buildReportMessage(daybook.NewDaybookReport(db, c.config.Statuses)), nil
 	}
 
-	storyReports := make([]slackapi.RichTextElement, 0)
-	for _, story := range epic.Stories {
-		storyReports = append(storyReports, c.formatStoryReport(story, indent+1)...)
+	buf := bytes.Buffer{}
+	err := c.config.Template.Execute(&buf, db)
+	if err != nil {
+		return nil, err
 	}
 
-	contents := []slackapi.RichTextElement{
-		slackapi.NewRichTextList(slackapi.RTEListBullet, indent,
-			slackapi.NewRichTextSection(listHeader...),
-		),
-	}
-	contents = append(contents, storyReports...)
-
-	return slackapi.NewRichTextBlock("",
-		contents...,
-	)
+	return decodeBlocks(buf.Bytes())
 }
 
-func (c *Client) formatStoryReport(story *daybook.Story, indent int) []slackapi.RichTextElement {
-	listHeader := []slackapi.RichTextSectionElement{
-		slackapi.NewRichTextSectionEmojiElement("jira-story", 0, nil),
-		slackapi.NewRichTextSectionLinkElement(story.Link.String(), " "+story.Title, nil),
-	}
-
-	contents := []slackapi.RichTextElement{
-		slackapi.NewRichTextSection(listHeader...),
+// decodeBlocks decodes the blocks of a message rendered by a template.
+func decodeBlocks(data []byte) ([]slackapi.Block, error) {
+	var message struct {
+		Blocks *slackapi.Blocks `json:"blocks"`
 	}
 
-	return []slackapi.RichTextElement{
-		slackapi.NewRichTextList(slackapi.RTEListBullet, indent, contents...),
-		c.formatSubtaskReport(story.Subtasks, indent+1),
+	err := json.Unmarshal(data, &message)
+	if err != nil {
+		return nil, fmt.Errorf("decoding daybook message blocks: %w", err)
 	}
-}
-
-func (c *Client) formatSubtaskReport(subtasks []*daybook.Task, indent int) slackapi.RichTextElement {
-	listItems := make([]slackapi.RichTextElement, 0)
-	for _, task := range subtasks {
-		listItems = append(listItems, slackapi.NewRichTextSection(
-			slackapi.NewRichTextSectionEmojiElement("jira-subtask", 0, nil),
-			slackapi.NewRichTextSectionLinkElement(task.Link.String(), " "+task.Title, nil),
-		))
+	if message.Blocks == nil || len(message.Blocks.BlockSet) == 0 {
+		return nil, errors.New("decoding daybook message blocks: the message has no blocks")
 	}
 
-	return slackapi.NewRichTextList(slackapi.RTEListBullet, indent, listItems...)
-}
-
-func (c *Client) formatPlannedTask(task *daybook.Task, _ int) slackapi.Block {
-	return slackapi.NewRichTextBlock("",
-		slackapi.NewRichTextSection(
-			slackapi.NewRichTextSectionTextElement(task.Title, nil),
-		),
-	)
+	return message.Blocks.BlockSet, nil
 }
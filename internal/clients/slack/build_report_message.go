@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"fmt"
+
+	slackapi "github.com/zioyero/go-slack"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// buildReportMessage translates the report to blocks. Each section is a heading followed by a
+// single rich text block, which keeps long daybooks within Slack's limit on blocks per message.
+func (c *Client) buildReportMessage(report *daybook.Report) []slackapi.Block {
+	blocks := make([]slackapi.Block, 0)
+
+	title := fmt.Sprintf("*%s*", report.Title)
+	if report.User != nil {
+		title = fmt.Sprintf("<@%s> %s", report.User.SlackID, title)
+	}
+
+	blocks = append(blocks, c.mrkdwnSection(title))
+
+	for _, section := range report.Sections {
+		heading := fmt.Sprintf("*%s*", section.Title)
+		if section.Emoji != "" {
+			heading = fmt.Sprintf(":%s: %s", section.Emoji, heading)
+		}
+
+		blocks = append(blocks, c.mrkdwnSection(heading))
+
+		if len(section.Items) == 0 {
+			continue
+		}
+
+		lists := make([]slackapi.RichTextElement, 0)
+		for _, item := range section.Items {
+			lists = append(lists, c.formatReportItem(item, 0)...)
+		}
+
+		blocks = append(blocks, slackapi.NewRichTextBlock("", lists...))
+	}
+
+	for _, warning := range report.Warnings {
+		blocks = append(blocks,
+			slackapi.NewContextBlock("",
+				slackapi.NewTextBlockObject("mrkdwn", ":warning: "+warning, false, false),
+			),
+		)
+	}
+
+	return blocks
+}
+
+// formatReportItem formats the item and its children as lists, each indented below its parent.
+func (c *Client) formatReportItem(item *daybook.ReportItem, indent int) []slackapi.RichTextElement {
+	elements := make([]slackapi.RichTextSectionElement, 0)
+
+	if item.Emoji != "" {
+		elements = append(elements,
+			slackapi.NewRichTextSectionEmojiElement(item.Emoji, 0, nil),
+			slackapi.NewRichTextSectionTextElement(" ", nil),
+		)
+	}
+
+	if item.Badge != "" {
+		elements = append(elements, slackapi.NewRichTextSectionTextElement(item.Badge+": ", &slackapi.RichTextSectionTextStyle{Bold: true}))
+	}
+
+	if item.User != nil {
+		elements = append(elements,
+			slackapi.NewRichTextSectionUserElement(item.User.SlackID, nil),
+			slackapi.NewRichTextSectionTextElement(": ", nil),
+		)
+	}
+
+	for i, link := range item.Links {
+		if i > 0 {
+			elements = append(elements, slackapi.NewRichTextSectionTextElement(", ", nil))
+		}
+
+		if link.URL == nil {
+			elements = append(elements, slackapi.NewRichTextSectionTextElement(link.Text, nil))
+		} else {
+			elements = append(elements, slackapi.NewRichTextSectionLinkElement(link.URL.String(), link.Text, nil))
+		}
+	}
+
+	lists := []slackapi.RichTextElement{
+		slackapi.NewRichTextList(slackapi.RTEListBullet, indent, slackapi.NewRichTextSection(elements...)),
+	}
+
+	for _, child := range item.Children {
+		lists = append(lists, c.formatReportItem(child, indent+1)...)
+	}
+
+	return lists
+}
+
+func (c *Client) mrkdwnSection(text string) slackapi.Block {
+	return slackapi.NewSectionBlock(
+		slackapi.NewTextBlockObject("mrkdwn", text, false, false),
+		nil,
+		nil,
+	)
+}
@@ -0,0 +1,10 @@
+package slack
+
+import (
+	slackapi "github.com/zioyero/go-slack"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+func (c *Client) buildWeeklySummaryMessage(summary *daybook.WeeklySummary) []slackapi.Block {
+	return c.buildReportMessage(daybook.NewWeeklyReport(summary))
+}
@@ -1,14 +1,23 @@
 package slack
 
 import (
+	"errors"
+
 	slackapi "github.com/zioyero/go-slack"
+	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
-const devNullChannel = "C07KPQHT7L7"
-
 type Config struct {
-	Token          string
-	DaybookChannel string
+	Token string
+	// Statuses determines the sections of the daybook message. DefaultStatusModel is used when nil.
+	Statuses *daybook.StatusModel
+	// Sent remembers which daybooks, weekly summaries and team digests were posted where, so that
+	// they're never posted twice. Optional.
+	Sent daybook.SentStore
+	// Template renders daybook messages as a JSON object with the message's blocks, as exported by
+	// Slack's Block Kit Builder. Daybooks are laid out like weekly summaries and team digests when
+	// it is nil.
+	Template *daybook.DaybookTemplate
 }
 
 type Client struct {
@@ -17,8 +26,23 @@ type Client struct {
 }
 
 func NewClient(cfg *Config) *Client {
+	if cfg.Statuses == nil {
+		cfg.Statuses = daybook.DefaultStatusModel()
+	}
+
 	return &Client{
 		slack:  slackapi.New(cfg.Token),
 		config: cfg,
 	}
 }
+
+// wrapError marks Slack rate limits as retryable after the delay Slack asked for. Slack's other
+// transient errors already report themselves as retryable.
+func wrapError(err error) error {
+	var rateLimited *slackapi.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return &daybook.RetryableError{Err: err, RetryAfter: rateLimited.RetryAfter}
+	}
+
+	return err
+}
@@ -10,26 +10,71 @@ import (
 	"github.com/zioyero/jira-daybot/internal/daybook"
 )
 
+// SendDaybookEntry posts the daybook to each of the user's channels, as a reply in a thread under a
+// header message. If the day's daybook was already posted to a channel, the existing reply is
+// updated in place instead, and a thread whose header was posted without its reply gets the reply
+// rather than a second thread.
 func (c *Client) SendDaybookEntry(ctx context.Context, db *daybook.Daybook) error {
-	blocks := c.buildDaybookMessage(db)
+	blocks, err := c.buildDaybookMessage(db)
+	if err != nil {
+		return err
+	}
+
+	day := db.Day.Format(daybook.DayFormat)
 
 	for _, channel := range db.User.DaybookChannels {
-		headerBlock := slackapi.NewSectionBlock(
-			slackapi.NewTextBlockObject("mrkdwn",
-				fmt.Sprintf(":thread: <@%s> *Daybook for %s*", db.User.SlackHandle, db.Day.Format("2006-01-02")), false, false,
-			),
-			nil,
-			nil,
-		)
-
-		_, ts, err := c.slack.PostMessageContext(ctx, channel, slackapi.MsgOptionBlocks(headerBlock))
+		sent, err := c.sentMessage(ctx, &daybook.SentMessage{Kind: daybook.SentKindDaybook, SlackID: db.User.SlackID, Day: day, Channel: channel})
 		if err != nil {
-			return fmt.Errorf("sending slack message: %w", err)
+			return err
+		}
+
+		if sent.MessageTS != "" {
+			_, _, _, err = c.slack.UpdateMessageContext(ctx, channel, sent.MessageTS, slackapi.MsgOptionBlocks(blocks...))
+			if err != nil {
+				return fmt.Errorf("updating slack message: %w", wrapError(err))
+			}
+
+			err = c.recordSentMessage(ctx, sent)
+			if err != nil {
+				return err
+			}
+
+			color.Yellow("Updated the daybook for @%s on %s already posted to %s", db.User.SlackHandle, day, channel)
+
+			time.Sleep(1 * time.Second) // Sleep for 1 second to avoid rate limiting
+			continue
+		}
+
+		if sent.HeaderTS == "" {
+			headerBlock := slackapi.NewSectionBlock(
+				slackapi.NewTextBlockObject("mrkdwn",
+					fmt.Sprintf(":thread: <@%s> *Daybook for %s*", db.User.SlackHandle, day), false, false,
+				),
+				nil,
+				nil,
+			)
+
+			_, ts, err := c.slack.PostMessageContext(ctx, channel, slackapi.MsgOptionBlocks(headerBlock))
+			if err != nil {
+				return fmt.Errorf("sending slack message: %w", wrapError(err))
+			}
+
+			sent.HeaderTS = ts
+			err = c.recordSentMessage(ctx, sent)
+			if err != nil {
+				return err
+			}
 		}
 
-		_, _, err = c.slack.PostMessageContext(ctx, channel, slackapi.MsgOptionBlocks(blocks...), slackapi.MsgOptionTS(ts))
+		_, ts, err := c.slack.PostMessageContext(ctx, channel, slackapi.MsgOptionBlocks(blocks...), slackapi.MsgOptionTS(sent.HeaderTS))
 		if err != nil {
-			return fmt.Errorf("sending slack message: %w", err)
+			return fmt.Errorf("sending slack message: %w", wrapError(err))
+		}
+
+		sent.MessageTS = ts
+		err = c.recordSentMessage(ctx, sent)
+		if err != nil {
+			return err
 		}
 
 		time.Sleep(1 * time.Second) // Sleep for 1 second to avoid rate limiting
@@ -40,8 +85,65 @@ func (c *Client) SendDaybookEntry(ctx context.Context, db *daybook.Daybook) erro
 	return nil
 }
 
+// sentMessage returns the stored record of the message identified by the key's kind, owner, day and
+// channel, or the key itself if the message hasn't been posted yet or no store is configured.
+func (c *Client) sentMessage(ctx context.Context, key *daybook.SentMessage) (*daybook.SentMessage, error) {
+	if c.config.Sent != nil {
+		sent, err := c.config.Sent.SentMessage(ctx, key.Kind, key.Owner(), key.Day, key.Channel)
+		if err != nil {
+			return nil, fmt.Errorf("getting sent message: %w", err)
+		}
+
+		if sent != nil {
+			return sent, nil
+		}
+	}
+
+	return key, nil
+}
+
+// postOrUpdateMessage posts the blocks to the sent message's channel, or updates the message if it
+// was already posted, and records it. It reports whether the message was updated.
+func (c *Client) postOrUpdateMessage(ctx context.Context, sent *daybook.SentMessage, blocks []slackapi.Block) (bool, error) {
+	if sent.MessageTS != "" {
+		_, _, _, err := c.slack.UpdateMessageContext(ctx, sent.Channel, sent.MessageTS, slackapi.MsgOptionBlocks(blocks...))
+		if err != nil {
+			return false, fmt.Errorf("updating slack message: %w", wrapError(err))
+		}
+
+		return true, c.recordSentMessage(ctx, sent)
+	}
+
+	_, ts, err := c.slack.PostMessageContext(ctx, sent.Channel, slackapi.MsgOptionBlocks(blocks...))
+	if err != nil {
+		return false, fmt.Errorf("sending slack message: %w", wrapError(err))
+	}
+
+	sent.MessageTS = ts
+
+	return false, c.recordSentMessage(ctx, sent)
+}
+
+func (c *Client) recordSentMessage(ctx context.Context, sent *daybook.SentMessage) error {
+	if c.config.Sent == nil {
+		return nil
+	}
+
+	sent.SentAt = time.Now()
+
+	err := c.config.Sent.RecordSentMessage(ctx, sent)
+	if err != nil {
+		return fmt.Errorf("recording sent message: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) SendDaybookDMReminder(ctx context.Context, db *daybook.Daybook) error {
-	blocks := c.buildDaybookMessage(db)
+	blocks, err := c.buildDaybookMessage(db)
+	if err != nil {
+		return err
+	}
 
 	reviewDaybookReminder := slackapi.NewSectionBlock(
 		slackapi.NewTextBlockObject("mrkdwn",
@@ -52,9 +154,9 @@ func (c *Client) SendDaybookDMReminder(ctx context.Context, db *daybook.Daybook)
 	)
 	blocks = append(blocks, reviewDaybookReminder)
 
-	_, _, err := c.slack.PostMessageContext(ctx, db.User.SlackID, slackapi.MsgOptionBlocks(blocks...))
+	_, _, err = c.slack.PostMessageContext(ctx, db.User.SlackID, slackapi.MsgOptionBlocks(blocks...))
 	if err != nil {
-		return fmt.Errorf("sending slack message: %w", err)
+		return fmt.Errorf("sending slack message: %w", wrapError(err))
 	}
 
 	color.Green("Sent daybook reminder to Slack")
@@ -0,0 +1,36 @@
+package slack
+
+import (
+	"context"
+
+	"github.com/fatih/color"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// SendTeamDigest posts the team digest to the team's channel. If the day's digest was already posted,
+// the posted message is updated instead.
+func (c *Client) SendTeamDigest(ctx context.Context, digest *daybook.TeamDigest) error {
+	blocks := c.buildTeamDigestMessage(digest)
+
+	sent, err := c.sentMessage(ctx, &daybook.SentMessage{
+		Kind:    daybook.SentKindTeamDigest,
+		Team:    digest.Team.Name,
+		Day:     digest.Day.Format(daybook.DayFormat),
+		Channel: digest.Team.Channel,
+	})
+	if err != nil {
+		return err
+	}
+
+	updated, err := c.postOrUpdateMessage(ctx, sent, blocks)
+	if err != nil {
+		return err
+	}
+	if updated {
+		color.Yellow("Updated the %s digest already posted to %s", digest.Team.Name, digest.Team.Channel)
+	}
+
+	color.Green("Sent team digest to Slack")
+
+	return nil
+}
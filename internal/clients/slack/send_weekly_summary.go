@@ -0,0 +1,42 @@
+package slack
+
+import (
+	"context"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// SendWeeklySummary posts the weekly summary to each of the user's daybook channels. If the week's
+// summary was already posted to a channel, the posted message is updated instead.
+func (c *Client) SendWeeklySummary(ctx context.Context, summary *daybook.WeeklySummary) error {
+	blocks := c.buildWeeklySummaryMessage(summary)
+	week := summary.Week.Start.Format(daybook.DayFormat)
+
+	for _, channel := range summary.User.DaybookChannels {
+		sent, err := c.sentMessage(ctx, &daybook.SentMessage{
+			Kind:    daybook.SentKindWeeklySummary,
+			SlackID: summary.User.SlackID,
+			Day:     week,
+			Channel: channel,
+		})
+		if err != nil {
+			return err
+		}
+
+		updated, err := c.postOrUpdateMessage(ctx, sent, blocks)
+		if err != nil {
+			return err
+		}
+		if updated {
+			color.Yellow("Updated the weekly summary for @%s already posted to %s", summary.User.SlackHandle, channel)
+		}
+
+		time.Sleep(1 * time.Second) // Sleep for 1 second to avoid rate limiting
+	}
+
+	color.Green("Sent weekly summary to Slack")
+
+	return nil
+}
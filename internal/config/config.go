@@ -0,0 +1,632 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the daemon configuration loaded from the config file. Secrets such as API tokens are
+// not part of the file and are still read from the environment.
+type Config struct {
+	Jira      Jira      `yaml:"jira"`
+	Schedule  Schedule  `yaml:"schedule"`
+	Statuses  *Statuses `yaml:"statuses"`
+	Delivery  Delivery  `yaml:"delivery"`
+	History   History   `yaml:"history"`
+	Templates Templates `yaml:"templates"`
+	Users     []User    `yaml:"users"`
+	Teams     []Team    `yaml:"teams"`
+}
+
+// History controls where generated daybooks are kept.
+type History struct {
+	// Database is the path of the SQLite database storing every sent daybook.
+	Database string `yaml:"database"`
+}
+
+// Templates optionally replace the layout of daybook entries. Each is the path of a text/template
+// file, executed with daybook.TemplateData and able to use the helpers of daybook.TemplateFuncs.
+type Templates struct {
+	// Text renders daybooks for text outputs, such as stdout.
+	Text string `yaml:"text"`
+	// Slack renders daybook messages as a JSON object with the message's blocks, as exported by
+	// Slack's Block Kit Builder.
+	Slack string `yaml:"slack"`
+}
+
+// TextTemplate reads and parses the text template, returning nil when none is configured.
+func (t Templates) TextTemplate(statuses *daybook.StatusModel) (*daybook.DaybookTemplate, error) {
+	return loadTemplate(t.Text, statuses)
+}
+
+// SlackTemplate reads and parses the Slack template, returning nil when none is configured.
+func (t Templates) SlackTemplate(statuses *daybook.StatusModel) (*daybook.DaybookTemplate, error) {
+	return loadTemplate(t.Slack, statuses)
+}
+
+// validate renders a sample daybook with each configured template, so that a template that fails to
+// execute is caught before anything is sent. Whether the Slack template renders valid blocks is
+// checked by the Slack client.
+func (t Templates) validate(statuses *daybook.StatusModel) error {
+	var errs []error
+
+	text, err := t.TextTemplate(statuses)
+	if err == nil && text != nil {
+		err = text.ExecuteSample(io.Discard)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("templates.text: %w", err))
+	}
+
+	slackTemplate, err := t.SlackTemplate(statuses)
+	if err == nil && slackTemplate != nil {
+		err = slackTemplate.ExecuteSample(io.Discard)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("templates.slack: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func loadTemplate(path string, statuses *daybook.StatusModel) (*daybook.DaybookTemplate, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+
+	return daybook.ParseDaybookTemplate(filepath.Base(path), string(text), statuses)
+}
+
+// Delivery controls how batch sends are spread across users. Zero values use the defaults.
+type Delivery struct {
+	// Concurrency is the number of users whose daybooks are generated and sent at once.
+	Concurrency int `yaml:"concurrency"`
+	// UserTimeout bounds the time spent on a single user's daybook.
+	UserTimeout time.Duration `yaml:"user_timeout"`
+	// Retry controls how transient Jira and Slack failures are retried.
+	Retry Retry `yaml:"retry"`
+	// DeadLetterFile is where sends that failed after all retries are recorded for replaying.
+	DeadLetterFile string `yaml:"dead_letter_file"`
+	// SentFile records which daybooks, weekly summaries and team digests were posted to which
+	// channels, so that re-running them or restarting the daemon updates the posted message rather
+	// than posting it twice.
+	SentFile string `yaml:"sent_file"`
+	// SentRetention is how long records of posted messages are kept. Sending a message again after
+	// its record was pruned posts it anew.
+	SentRetention time.Duration `yaml:"sent_retention"`
+}
+
+type Retry struct {
+	Attempts       int           `yaml:"attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// RetryPolicy returns the configured retry policy, using the defaults for unset values.
+func (d Delivery) RetryPolicy() daybook.RetryPolicy {
+	policy := daybook.DefaultRetryPolicy()
+	if d.Retry.Attempts > 0 {
+		policy.Attempts = d.Retry.Attempts
+	}
+	if d.Retry.InitialBackoff > 0 {
+		policy.InitialBackoff = d.Retry.InitialBackoff
+	}
+	if d.Retry.MaxBackoff > 0 {
+		policy.MaxBackoff = d.Retry.MaxBackoff
+	}
+
+	return policy
+}
+
+type Jira struct {
+	Instance string `yaml:"instance"`
+	Username string `yaml:"username"`
+	// Project is a single project key, kept for older config files. Prefer Projects.
+	Project  string   `yaml:"project"`
+	Projects []string `yaml:"projects"`
+	// Query optionally replaces the default JQL query for a user's tasks. It's a text/template with
+	// the {{.Projects}}, {{.Assignee}}, {{.Since}}, {{.Until}} and {{.Statuses}} placeholders.
+	Query string `yaml:"query"`
+	// MaxResults caps the number of issues fetched per search. Daybooks note when it was reached.
+	MaxResults int `yaml:"max_results"`
+	// CacheTTL is how long tasks looked up by key, such as parent stories and epics, are cached.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// HierarchyLevels maps issue type names to their level in the Jira issue hierarchy, such as
+	// Initiative: 2, overriding the levels Jira reports for the issue types.
+	HierarchyLevels map[string]int `yaml:"hierarchy_levels"`
+}
+
+// AllProjects returns the configured project keys, including the legacy single project.
+func (j Jira) AllProjects() []string {
+	if j.Project == "" {
+		return j.Projects
+	}
+
+	return append([]string{j.Project}, j.Projects...)
+}
+
+// Statuses maps Jira statuses to the sections of the daybook. When omitted, the default Jira
+// software workflow is used.
+type Statuses struct {
+	Sections []StatusSection `yaml:"sections"`
+	Exclude  []string        `yaml:"exclude"`
+}
+
+type StatusSection struct {
+	Name     string   `yaml:"name"`
+	Label    string   `yaml:"label"`
+	Emoji    string   `yaml:"emoji"`
+	Statuses []string `yaml:"statuses"`
+	Category string   `yaml:"category"`
+	Recent   bool     `yaml:"recent"`
+	Review   bool     `yaml:"review"`
+}
+
+// Schedule holds the cron expressions for the daybook jobs, evaluated in Timezone.
+type Schedule struct {
+	Timezone string `yaml:"timezone"`
+	Daybook  string `yaml:"daybook"`
+	Reminder string `yaml:"reminder"`
+	// Weekly is when each user's weekly summary is sent, defaulting to Friday afternoon.
+	Weekly string `yaml:"weekly"`
+	// Team is when team digests are posted. When it isn't set, each team's digest is posted right
+	// after its members' daybooks are sent.
+	Team string `yaml:"team"`
+}
+
+type User struct {
+	SlackHandle     string   `yaml:"slack_handle"`
+	SlackID         string   `yaml:"slack_id"`
+	AtlassianID     string   `yaml:"atlassian_id"`
+	DaybookChannels []string `yaml:"daybook_channels"`
+
+	// Timezone is the user's local time zone, defaulting to the schedule's time zone.
+	Timezone string `yaml:"timezone"`
+	// Schedule optionally overrides the default crontabs for this user. The crontabs are evaluated
+	// in the user's time zone.
+	Schedule UserSchedule `yaml:"schedule"`
+}
+
+// Team is a group of users whose daybooks are rolled up into a digest posted to the team's channel.
+type Team struct {
+	Name    string `yaml:"name"`
+	Channel string `yaml:"channel"`
+	// Members are the slack handles of the team's users.
+	Members []string `yaml:"members"`
+	// Timezone is the time zone the team's schedule is evaluated in, defaulting to the schedule's
+	// time zone.
+	Timezone string `yaml:"timezone"`
+	// Schedule optionally overrides the default crontab of the team's digest.
+	Schedule string `yaml:"schedule"`
+}
+
+type UserSchedule struct {
+	Daybook  string `yaml:"daybook"`
+	Reminder string `yaml:"reminder"`
+	Weekly   string `yaml:"weekly"`
+}
+
+// Load reads and validates the config file at the given path.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening config file: %w", err)
+	}
+	defer f.Close()
+
+	cfg := &Config{}
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	err = dec.Decode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if cfg.Jira.CacheTTL == 0 {
+		cfg.Jira.CacheTTL = time.Hour
+	}
+
+	if cfg.Delivery.DeadLetterFile == "" {
+		cfg.Delivery.DeadLetterFile = "dead_letters.json"
+	}
+	if cfg.Delivery.SentFile == "" {
+		cfg.Delivery.SentFile = "sent_messages.json"
+	}
+	if cfg.Delivery.SentRetention == 0 {
+		cfg.Delivery.SentRetention = 30 * 24 * time.Hour
+	}
+
+	if cfg.History.Database == "" {
+		cfg.History.Database = "daybook.db"
+	}
+
+	if cfg.Schedule.Timezone == "" {
+		cfg.Schedule.Timezone = "America/Los_Angeles"
+	}
+	if cfg.Schedule.Weekly == "" {
+		cfg.Schedule.Weekly = "0 17 * * 5"
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the config for missing or malformed values, reporting every problem found
+// rather than stopping at the first one.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Jira.Instance == "" {
+		errs = append(errs, errors.New("jira.instance is required"))
+	}
+	if len(c.Jira.AllProjects()) == 0 && c.Jira.Query == "" {
+		errs = append(errs, errors.New("jira.projects or jira.query is required"))
+	}
+	if c.Jira.MaxResults < 0 {
+		errs = append(errs, errors.New("jira.max_results must not be negative"))
+	}
+	for name, level := range c.Jira.HierarchyLevels {
+		if level < daybook.LevelSubtask {
+			errs = append(errs, fmt.Errorf("jira.hierarchy_levels: level of %q must be at least %d", name, daybook.LevelSubtask))
+		}
+	}
+	if c.Jira.Query != "" {
+		query, err := template.New("query").Parse(c.Jira.Query)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("jira.query: %w", err))
+		case len(c.Jira.AllProjects()) == 0 && usesField(query.Tree.Root, "Projects"):
+			// "project IN ()" is invalid JQL
+			errs = append(errs, errors.New("jira.query uses {{.Projects}}, which requires jira.projects"))
+		}
+	}
+
+	errs = append(errs, c.Templates.validate(c.StatusModel()))
+
+	errs = append(errs, validateCrontab("schedule.daybook", c.Schedule.Daybook))
+	errs = append(errs, validateCrontab("schedule.reminder", c.Schedule.Reminder))
+	errs = append(errs, validateCrontab("schedule.weekly", c.Schedule.Weekly))
+	if c.Schedule.Team != "" {
+		errs = append(errs, validateCrontab("schedule.team", c.Schedule.Team))
+	}
+	if _, err := c.Schedule.Location(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Delivery.Concurrency < 0 {
+		errs = append(errs, errors.New("delivery.concurrency must not be negative"))
+	}
+	if c.Delivery.SentRetention < 0 {
+		errs = append(errs, errors.New("delivery.sent_retention must not be negative"))
+	}
+	if c.Delivery.UserTimeout < 0 {
+		errs = append(errs, errors.New("delivery.user_timeout must not be negative"))
+	}
+
+	if c.Delivery.Retry.Attempts < 0 {
+		errs = append(errs, errors.New("delivery.retry.attempts must not be negative"))
+	}
+
+	if c.Statuses != nil {
+		errs = append(errs, c.Statuses.validate())
+	}
+
+	if len(c.Users) == 0 {
+		errs = append(errs, errors.New("at least one user is required"))
+	}
+
+	seen := make(map[string]int)
+	seenHandles := make(map[string]int)
+	for i, u := range c.Users {
+		name := fmt.Sprintf("users[%d]", i)
+		if u.SlackHandle != "" {
+			name += fmt.Sprintf(" (@%s)", u.SlackHandle)
+		}
+
+		if u.SlackHandle == "" {
+			errs = append(errs, fmt.Errorf("%s: slack_handle is required", name))
+		} else if j, ok := seenHandles[u.SlackHandle]; ok {
+			errs = append(errs, fmt.Errorf("%s: slack_handle is already used by users[%d]", name, j))
+		} else {
+			seenHandles[u.SlackHandle] = i
+		}
+		if u.AtlassianID == "" {
+			errs = append(errs, fmt.Errorf("%s: atlassian_id is required", name))
+		}
+		if u.SlackID == "" {
+			errs = append(errs, fmt.Errorf("%s: slack_id is required", name))
+		} else if j, ok := seen[u.SlackID]; ok {
+			errs = append(errs, fmt.Errorf("%s: slack_id %s is already used by users[%d]", name, u.SlackID, j))
+		} else {
+			seen[u.SlackID] = i
+		}
+		if u.Timezone != "" {
+			if _, err := time.LoadLocation(u.Timezone); err != nil {
+				errs = append(errs, fmt.Errorf("%s: timezone: %w", name, err))
+			}
+		}
+		if u.Schedule.Daybook != "" {
+			errs = append(errs, validateCrontab(name+": schedule.daybook", u.Schedule.Daybook))
+		}
+		if u.Schedule.Reminder != "" {
+			errs = append(errs, validateCrontab(name+": schedule.reminder", u.Schedule.Reminder))
+		}
+		if u.Schedule.Weekly != "" {
+			errs = append(errs, validateCrontab(name+": schedule.weekly", u.Schedule.Weekly))
+		}
+	}
+
+	errs = append(errs, c.validateTeams())
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validateTeams() error {
+	var errs []error
+
+	handles := make(map[string]bool)
+	for _, u := range c.Users {
+		handles[u.SlackHandle] = true
+	}
+
+	names := make(map[string]bool)
+	for i, t := range c.Teams {
+		name := fmt.Sprintf("teams[%d]", i)
+		if t.Name != "" {
+			name += fmt.Sprintf(" (%s)", t.Name)
+		}
+
+		if t.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", name))
+		} else if names[t.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate team name", name))
+		}
+		names[t.Name] = true
+
+		if t.Channel == "" {
+			errs = append(errs, fmt.Errorf("%s: channel is required", name))
+		}
+		if len(t.Members) == 0 {
+			errs = append(errs, fmt.Errorf("%s: at least one member is required", name))
+		}
+		for _, member := range t.Members {
+			if !handles[member] {
+				errs = append(errs, fmt.Errorf("%s: member @%s is not a configured user", name, member))
+			}
+		}
+		if t.Timezone != "" {
+			if _, err := time.LoadLocation(t.Timezone); err != nil {
+				errs = append(errs, fmt.Errorf("%s: timezone: %w", name, err))
+			}
+		}
+		if t.Schedule != "" {
+			errs = append(errs, validateCrontab(name+": schedule", t.Schedule))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// usesField reports whether the template node refers to the field of the template's data, such as
+// {{.Projects}}.
+func usesField(node parse.Node, field string) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		return slices.ContainsFunc(n.Nodes, func(node parse.Node) bool { return usesField(node, field) })
+	case *parse.ActionNode:
+		return usesField(n.Pipe, field)
+	case *parse.IfNode:
+		return usesField(&n.BranchNode, field)
+	case *parse.RangeNode:
+		return usesField(&n.BranchNode, field)
+	case *parse.WithNode:
+		return usesField(&n.BranchNode, field)
+	case *parse.BranchNode:
+		return usesField(n.Pipe, field) || usesField(n.List, field) || usesField(n.ElseList, field)
+	case *parse.TemplateNode:
+		return usesField(n.Pipe, field)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		return slices.ContainsFunc(n.Cmds, func(cmd *parse.CommandNode) bool { return usesField(cmd, field) })
+	case *parse.CommandNode:
+		return slices.ContainsFunc(n.Args, func(arg parse.Node) bool { return usesField(arg, field) })
+	case *parse.ChainNode:
+		return usesField(n.Node, field)
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == field
+	case *parse.VariableNode:
+		return len(n.Ident) > 1 && n.Ident[0] == "$" && n.Ident[1] == field
+	default:
+		return false
+	}
+}
+
+func validateCrontab(field, crontab string) error {
+	if crontab == "" {
+		return fmt.Errorf("%s is required", field)
+	}
+
+	_, err := cron.ParseStandard(crontab)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+
+	return nil
+}
+
+func (s *Statuses) validate() error {
+	var errs []error
+
+	if len(s.Sections) == 0 {
+		errs = append(errs, errors.New("statuses.sections: at least one section is required"))
+	}
+
+	names := make(map[string]bool)
+	mapped := make(map[string]string)
+	categories := make(map[string]string)
+	for i, section := range s.Sections {
+		field := fmt.Sprintf("statuses.sections[%d]", i)
+
+		if section.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		} else if names[section.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate section name %q", field, section.Name))
+		}
+		names[section.Name] = true
+
+		if section.Label == "" {
+			errs = append(errs, fmt.Errorf("%s: label is required", field))
+		}
+		if len(section.Statuses) == 0 && section.Category == "" {
+			errs = append(errs, fmt.Errorf("%s: statuses or category is required", field))
+		}
+
+		for _, status := range section.Statuses {
+			if other, ok := mapped[status]; ok {
+				errs = append(errs, fmt.Errorf("%s: status %q is already mapped to section %q", field, status, other))
+			}
+			mapped[status] = section.Name
+		}
+
+		switch section.Category {
+		case "":
+		case daybook.CategoryToDo, daybook.CategoryInProgress, daybook.CategoryDone:
+			if other, ok := categories[section.Category]; ok {
+				errs = append(errs, fmt.Errorf("%s: category %q is already mapped to section %q", field, section.Category, other))
+			}
+			categories[section.Category] = section.Name
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown category %q, expected %q, %q or %q", field, section.Category,
+				daybook.CategoryToDo, daybook.CategoryInProgress, daybook.CategoryDone))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// StatusModel returns the configured status model, or the default model if none is configured.
+func (c *Config) StatusModel() *daybook.StatusModel {
+	if c.Statuses == nil {
+		return daybook.DefaultStatusModel()
+	}
+
+	model := &daybook.StatusModel{Excluded: c.Statuses.Exclude}
+	for _, section := range c.Statuses.Sections {
+		model.Sections = append(model.Sections, &daybook.StatusSection{
+			Name:     section.Name,
+			Label:    section.Label,
+			Emoji:    section.Emoji,
+			Statuses: section.Statuses,
+			Category: section.Category,
+			Recent:   section.Recent,
+			Review:   section.Review,
+		})
+	}
+
+	return model
+}
+
+// Location returns the time zone the schedule's cron expressions are evaluated in.
+func (s Schedule) Location() (*time.Location, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("schedule.timezone: %w", err)
+	}
+
+	return loc, nil
+}
+
+// DaybookUsers converts the configured users into the daybook domain model. Users without a time
+// zone of their own get the schedule's time zone.
+func (c *Config) DaybookUsers() ([]*daybook.User, error) {
+	users := make([]*daybook.User, 0, len(c.Users))
+	for _, u := range c.Users {
+		timezone := u.Timezone
+		if timezone == "" {
+			timezone = c.Schedule.Timezone
+		}
+
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("loading time zone for @%s: %w", u.SlackHandle, err)
+		}
+
+		users = append(users, &daybook.User{
+			SlackHandle:     u.SlackHandle,
+			SlackID:         u.SlackID,
+			AtlassianID:     u.AtlassianID,
+			DaybookChannels: u.DaybookChannels,
+			Timezone:        loc,
+			DaybookCrontab:  u.Schedule.Daybook,
+			ReminderCrontab: u.Schedule.Reminder,
+			WeeklyCrontab:   u.Schedule.Weekly,
+		})
+	}
+
+	return users, nil
+}
+
+// DaybookTeams converts the configured teams into the daybook domain model, resolving their members
+// among the given users by slack handle. Teams without a time zone of their own get the schedule's
+// time zone.
+func (c *Config) DaybookTeams(users []*daybook.User) ([]*daybook.Team, error) {
+	byHandle := make(map[string]*daybook.User)
+	for _, u := range users {
+		byHandle[u.SlackHandle] = u
+	}
+
+	teams := make([]*daybook.Team, 0, len(c.Teams))
+	for _, t := range c.Teams {
+		loc, err := time.LoadLocation(cmp.Or(t.Timezone, c.Schedule.Timezone))
+		if err != nil {
+			return nil, fmt.Errorf("loading time zone for team %s: %w", t.Name, err)
+		}
+
+		members := make([]*daybook.User, 0, len(t.Members))
+		for _, handle := range t.Members {
+			member, ok := byHandle[handle]
+			if !ok {
+				return nil, fmt.Errorf("team %s: unknown member @%s", t.Name, handle)
+			}
+
+			members = append(members, member)
+		}
+
+		teams = append(teams, &daybook.Team{
+			Name:     t.Name,
+			Channel:  t.Channel,
+			Members:  members,
+			Timezone: loc,
+			Crontab:  t.Schedule,
+		})
+	}
+
+	return teams, nil
+}
@@ -0,0 +1,129 @@
+package daybook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// DefaultConcurrency is the default number of users processed at once by batch sends.
+	DefaultConcurrency = 4
+	// DefaultUserTimeout is the default time allowed for generating and sending a single user's
+	// daybook.
+	DefaultUserTimeout = 2 * time.Minute
+)
+
+type ResultStatus string
+
+const (
+	StatusSucceeded ResultStatus = "succeeded"
+	StatusFailed    ResultStatus = "failed"
+)
+
+// UserResult is the outcome of a batch operation for a single user.
+type UserResult struct {
+	User     *User
+	Status   ResultStatus
+	Err      error
+	Duration time.Duration
+}
+
+// BatchResult is the outcome of a batch operation across users, with one result per user in the
+// order the users were given.
+type BatchResult struct {
+	Operation string
+	Results   []UserResult
+}
+
+// Failed returns the results of the users the operation failed for.
+func (r *BatchResult) Failed() []UserResult {
+	failed := make([]UserResult, 0)
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// Err returns an error describing every user the operation failed for, or nil if it succeeded for
+// all of them.
+func (r *BatchResult) Err() error {
+	errs := make([]error, 0)
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("@%s: %w", result.User.SlackHandle, result.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// forEachUser runs fn for every user on a bounded pool of workers, giving each call its own
+// timeout. The results are in the same order as the users, regardless of when each call finished.
+func (s *Service) forEachUser(ctx context.Context, operation string, users []*User, fn func(context.Context, *User) error) *BatchResult {
+	results := make([]UserResult, len(users))
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(s.cfg.Concurrency, len(users)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				results[i] = s.runForUser(ctx, users[i], fn)
+			}
+		}()
+	}
+
+	for i := range users {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	result := &BatchResult{Operation: operation, Results: results}
+	logResult(result)
+
+	return result
+}
+
+func (s *Service) runForUser(ctx context.Context, user *User, fn func(context.Context, *User) error) UserResult {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.UserTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx, user)
+
+	status := StatusSucceeded
+	if err != nil {
+		status = StatusFailed
+	}
+
+	return UserResult{User: user, Status: status, Err: err, Duration: time.Since(start)}
+}
+
+// logResult reports which users the batch operation succeeded and failed for, in user order.
+func logResult(result *BatchResult) {
+	for _, r := range result.Results {
+		if r.Status == StatusFailed {
+			slog.Error(result.Operation, "User", r.User.SlackHandle, "Status", r.Status, "Duration", r.Duration, "Error", r.Err)
+			continue
+		}
+
+		slog.Info(result.Operation, "User", r.User.SlackHandle, "Status", r.Status, "Duration", r.Duration)
+	}
+
+	if failed := len(result.Failed()); failed > 0 {
+		color.Red("%s: %d of %d users failed", result.Operation, failed, len(result.Results))
+	} else {
+		color.Green("%s: all %d users succeeded", result.Operation, len(result.Results))
+	}
+}
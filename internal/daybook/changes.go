@@ -0,0 +1,149 @@
+package daybook
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Kinds of changes to a task since the previous daybook.
+const (
+	ChangeStarted  = "started"
+	ChangeMoved    = "moved"
+	ChangeFinished = "finished"
+	ChangeDropped  = "dropped"
+)
+
+// changeOrder is the order changes are reported in.
+var changeOrder = []string{ChangeFinished, ChangeMoved, ChangeStarted, ChangeDropped}
+
+// Changes are the transitions of the user's tasks since their previous daybook, so that readers see
+// movement rather than the same tasks in progress every day.
+type Changes struct {
+	// Since is the day of the previous daybook, formatted with DayFormat. It isn't necessarily
+	// yesterday, such as after a weekend.
+	Since string
+	Tasks []*TaskChange
+}
+
+// TaskChange is a task that moved between sections since the previous daybook.
+type TaskChange struct {
+	*Task
+
+	Kind string
+	// From is the section the task was previously reported in, and is nil for started tasks.
+	From *StatusSection
+	// To is the section the task is now reported in, and is nil for dropped tasks.
+	To *StatusSection
+}
+
+// Description summarizes the change for display, such as "Moved to In Code Review".
+func (c *TaskChange) Description() string {
+	switch c.Kind {
+	case ChangeStarted:
+		return "Started"
+	case ChangeMoved:
+		return "Moved to " + c.To.Label
+	case ChangeFinished:
+		return "Finished"
+	case ChangeDropped:
+		return "Dropped"
+	default:
+		return c.Kind
+	}
+}
+
+// DiffDaybooks returns the changes from the previous daybook to the current one. Only the tasks
+// reported on their own are compared, rather than the epics and parent stories shown for context.
+// Tasks are placed in sections by their status, using the current status model for both daybooks so
+// that a config change doesn't show up as movement.
+func DiffDaybooks(previous, current *HistoryEntry, statuses *StatusModel) *Changes {
+	before := reportedTasks(previous, statuses)
+	after := reportedTasks(current, statuses)
+
+	changes := &Changes{Since: previous.Day, Tasks: make([]*TaskChange, 0)}
+
+	for id, now := range after {
+		was, ok := before[id]
+
+		switch {
+		case ok && was.section == now.section:
+			continue
+		case now.done():
+			changes.Tasks = append(changes.Tasks, &TaskChange{Task: now.task, Kind: ChangeFinished, From: was.section, To: now.section})
+		case !ok:
+			changes.Tasks = append(changes.Tasks, &TaskChange{Task: now.task, Kind: ChangeStarted, To: now.section})
+		default:
+			changes.Tasks = append(changes.Tasks, &TaskChange{Task: now.task, Kind: ChangeMoved, From: was.section, To: now.section})
+		}
+	}
+
+	// Finished tasks are expected to leave the daybook once they're no longer recent
+	for id, was := range before {
+		if _, ok := after[id]; !ok && !was.done() {
+			changes.Tasks = append(changes.Tasks, &TaskChange{Task: was.task, Kind: ChangeDropped, From: was.section})
+		}
+	}
+
+	slices.SortFunc(changes.Tasks, func(a, b *TaskChange) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(changeOrder, a.Kind), slices.Index(changeOrder, b.Kind)),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return changes
+}
+
+type sectionedTask struct {
+	task    *Task
+	section *StatusSection
+}
+
+func (t sectionedTask) done() bool {
+	return isDone(t.task, t.section, true)
+}
+
+// reportedTasks returns the tasks of the entry that are reported in a section on their own, keyed
+// by ID. Epics, and parent stories whose own status is in another section than their subtasks',
+// are left out.
+func reportedTasks(entry *HistoryEntry, statuses *StatusModel) map[string]sectionedTask {
+	tasks := make(map[string]sectionedTask)
+	for _, task := range entry.Tasks {
+		if task.Kind == TaskKindEpic || task.Kind == TaskKindCreated {
+			continue
+		}
+
+		section, ok := statuses.Section(task.Task)
+		if !ok || section.Name != task.Section {
+			continue
+		}
+
+		tasks[task.ID] = sectionedTask{task: task.Task, section: section}
+	}
+
+	return tasks
+}
+
+// populateChanges compares the daybook with the user's previous daybook in the history. Failing to
+// read the history is logged rather than returned, leaving the changes out of the daybook.
+func (s *Service) populateChanges(ctx context.Context, db *Daybook) {
+	if s.history == nil {
+		return
+	}
+
+	current := NewHistoryEntry(db, s.cfg.Statuses)
+
+	previous, err := s.history.PreviousDaybook(ctx, current.SlackID, current.Day)
+	if err != nil {
+		slog.Error("Getting previous daybook", "User", db.User.SlackHandle, "Error", err)
+		return
+	}
+
+	if previous == nil {
+		return
+	}
+
+	db.Changes = DiffDaybooks(previous, current, s.cfg.Statuses)
+}
@@ -0,0 +1,122 @@
+package daybook
+
+import (
+	"slices"
+	"testing"
+)
+
+// historyTask returns a task as stored in the history, reported in the section of its status under
+// the default status model.
+func historyTask(id, status, kind string) *HistoryTask {
+	task := &Task{ID: id, Status: status, StatusCategory: CategoryInProgress}
+	if status == "Done" {
+		task.StatusCategory = CategoryDone
+	}
+
+	section := ""
+	if s, ok := DefaultStatusModel().Section(task); ok && kind != TaskKindCreated {
+		section = s.Name
+	}
+
+	return &HistoryTask{Task: task, Kind: kind, Section: section}
+}
+
+func TestDiffDaybooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []*HistoryTask
+		current  []*HistoryTask
+		want     []string
+	}{
+		{
+			name:     "unchanged",
+			previous: []*HistoryTask{historyTask("DAY-1", "In Progress", TaskKindStory)},
+			current:  []*HistoryTask{historyTask("DAY-1", "In Progress", TaskKindStory)},
+			want:     []string{},
+		},
+		{
+			name:     "added",
+			previous: []*HistoryTask{},
+			current:  []*HistoryTask{historyTask("DAY-1", "In Progress", TaskKindStory)},
+			want:     []string{"DAY-1 started"},
+		},
+		{
+			name:     "moved",
+			previous: []*HistoryTask{historyTask("DAY-1", "In Progress", TaskKindStory)},
+			current:  []*HistoryTask{historyTask("DAY-1", "Code Review", TaskKindStory)},
+			want:     []string{"DAY-1 moved"},
+		},
+		{
+			name:     "finished",
+			previous: []*HistoryTask{historyTask("DAY-1", "Code Review", TaskKindStory)},
+			current:  []*HistoryTask{historyTask("DAY-1", "Done", TaskKindStory)},
+			want:     []string{"DAY-1 finished"},
+		},
+		{
+			name:     "added and finished",
+			previous: []*HistoryTask{},
+			current:  []*HistoryTask{historyTask("DAY-1", "Done", TaskKindBug)},
+			want:     []string{"DAY-1 finished"},
+		},
+		{
+			name:     "removed",
+			previous: []*HistoryTask{historyTask("DAY-1", "Code Review", TaskKindStory)},
+			current:  []*HistoryTask{},
+			want:     []string{"DAY-1 dropped"},
+		},
+		{
+			name:     "removed after finishing",
+			previous: []*HistoryTask{historyTask("DAY-1", "Done", TaskKindStory)},
+			current:  []*HistoryTask{},
+			want:     []string{},
+		},
+		{
+			name:     "epics and created tasks",
+			previous: []*HistoryTask{historyTask("DAY-1", "In Progress", TaskKindEpic)},
+			current: []*HistoryTask{
+				historyTask("DAY-1", "Code Review", TaskKindEpic),
+				historyTask("DAY-2", "In Progress", TaskKindCreated),
+			},
+			want: []string{},
+		},
+		{
+			name: "ordered by kind and ID",
+			previous: []*HistoryTask{
+				historyTask("DAY-1", "In Progress", TaskKindStory),
+				historyTask("DAY-2", "In Progress", TaskKindSubtask),
+				historyTask("DAY-3", "In Progress", TaskKindStory),
+				historyTask("DAY-4", "Testing", TaskKindStory),
+			},
+			current: []*HistoryTask{
+				historyTask("DAY-3", "Done", TaskKindStory),
+				historyTask("DAY-2", "Testing", TaskKindSubtask),
+				historyTask("DAY-6", "In Progress", TaskKindStandalone),
+				historyTask("DAY-5", "In Progress", TaskKindStory),
+				historyTask("DAY-1", "Done", TaskKindStory),
+			},
+			want: []string{"DAY-1 finished", "DAY-3 finished", "DAY-2 moved", "DAY-5 started", "DAY-6 started", "DAY-4 dropped"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := &HistoryEntry{Day: "2024-06-03", Tasks: tt.previous}
+			current := &HistoryEntry{Day: "2024-06-04", Tasks: tt.current}
+
+			changes := DiffDaybooks(previous, current, DefaultStatusModel())
+
+			if changes.Since != previous.Day {
+				t.Errorf("Since = %q, want %q", changes.Since, previous.Day)
+			}
+
+			got := make([]string, 0, len(changes.Tasks))
+			for _, change := range changes.Tasks {
+				got = append(got, change.ID+" "+change.Kind)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package daybook
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Kinds of sends recorded as dead letters.
+const (
+	KindEntry    = "entry"
+	KindReminder = "reminder"
+	KindWeekly   = "weekly"
+)
+
+// DeadLetter records a send that failed permanently, after retries, so that it can be replayed.
+type DeadLetter struct {
+	Kind string `json:"kind"`
+	// Output is the output the send failed on, such as "slack". Dead letters recorded before outputs
+	// were recorded are Slack sends.
+	Output      string    `json:"output,omitempty"`
+	SlackID     string    `json:"slack_id"`
+	SlackHandle string    `json:"slack_handle"`
+	Day         time.Time `json:"day"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+type DeadLetterStore interface {
+	AddDeadLetter(ctx context.Context, letter *DeadLetter) error
+	DeadLetters(ctx context.Context) ([]*DeadLetter, error)
+	RemoveDeadLetter(ctx context.Context, letter *DeadLetter) error
+}
+
+// recordDeadLetters stores a dead letter for every user the batch send for today failed for.
+func (s *Service) recordDeadLetters(ctx context.Context, kind string, result *BatchResult) {
+	s.recordDeadLettersOn(ctx, kind, result, func(user *User) time.Time {
+		return time.Now().In(user.Location())
+	})
+}
+
+// recordDeadLettersOn stores a dead letter for every user the batch send failed for, dated with the
+// user's local day the send was for. Failing to store a dead letter is logged rather than returned,
+// since the send has already failed.
+func (s *Service) recordDeadLettersOn(ctx context.Context, kind string, result *BatchResult, day func(*User) time.Time) {
+	if s.deadLetters == nil {
+		return
+	}
+
+	for _, r := range result.Failed() {
+		letter := &DeadLetter{
+			Kind:        kind,
+			Output:      s.cfg.Output,
+			SlackID:     r.User.SlackID,
+			SlackHandle: r.User.SlackHandle,
+			Day:         day(r.User),
+			Error:       r.Err.Error(),
+			FailedAt:    time.Now(),
+		}
+
+		// The batch's context may have been cancelled, which shouldn't stop the failure from
+		// being recorded
+		err := s.deadLetters.AddDeadLetter(context.WithoutCancel(ctx), letter)
+		if err != nil {
+			slog.Error("Recording dead letter", "User", r.User.SlackHandle, "Kind", kind, "Error", err)
+		}
+	}
+}
+
+// ReplayDeadLetters resends every dead letter whose user is still configured and that failed on the
+// service's output, removing the ones that succeed. Dead letters of other outputs are kept for a
+// replay through their own output. Daybook entries are resent for the day they failed on, reconstructing past days from
+// the tasks' history. Reminders for a past day and weekly summaries of a past week can't be sent as
+// they would have been, so they're dropped rather than sending today's instead.
+func (s *Service) ReplayDeadLetters(ctx context.Context, users []*User) (*BatchResult, error) {
+	if s.deadLetters == nil {
+		return nil, fmt.Errorf("no dead letter store configured")
+	}
+
+	letters, err := s.deadLetters.DeadLetters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing dead letters: %w", err)
+	}
+
+	byID := make(map[string]*User)
+	for _, user := range users {
+		byID[user.SlackID] = user
+	}
+
+	result := &BatchResult{Operation: "Replaying dead letter"}
+	for _, letter := range letters {
+		output := cmp.Or(letter.Output, "slack")
+		if output != s.cfg.Output {
+			slog.Warn("Skipping dead letter of another output", "User", letter.SlackHandle, "Kind", letter.Kind, "Output", output)
+			continue
+		}
+
+		user, ok := byID[letter.SlackID]
+		if !ok {
+			slog.Warn("Skipping dead letter for unknown user", "User", letter.SlackHandle, "SlackID", letter.SlackID)
+			continue
+		}
+
+		send, ok := s.replay(user, letter)
+		if !ok {
+			slog.Warn("Dropping dead letter that can no longer be replayed", "User", letter.SlackHandle, "Kind", letter.Kind, "Day", letter.Day.Format(DayFormat))
+
+			err = s.deadLetters.RemoveDeadLetter(ctx, letter)
+			if err != nil {
+				return result, fmt.Errorf("removing dead letter: %w", err)
+			}
+
+			continue
+		}
+
+		r := s.runForUser(ctx, user, send)
+		result.Results = append(result.Results, r)
+
+		if r.Status == StatusSucceeded {
+			err = s.deadLetters.RemoveDeadLetter(ctx, letter)
+			if err != nil {
+				return result, fmt.Errorf("removing dead letter: %w", err)
+			}
+		}
+	}
+
+	logResult(result)
+
+	return result, nil
+}
+
+// replay returns the send that replays the letter, or false if the letter can't be replayed as it
+// would have been sent.
+func (s *Service) replay(user *User, letter *DeadLetter) (func(context.Context, *User) error, bool) {
+	now := time.Now().In(user.Location())
+	// The letter's day is the date in the user's time zone when the send failed
+	today := letter.Day.Format(DayFormat) == now.Format(DayFormat)
+
+	switch letter.Kind {
+	case KindEntry:
+		return func(ctx context.Context, user *User) error {
+			return s.sendDaybookEntry(ctx, user, letter.Day)
+		}, true
+	case KindReminder:
+		// A reminder previews the daybook that's about to be sent, which is pointless once the day
+		// has passed
+		return s.SendDaybookDMReminder, today
+	case KindWeekly:
+		// Weekly summaries can only be generated for the current week
+		sameWeek := WeekWindow(letter.Day).Start.Format(DayFormat) == WeekWindow(now).Start.Format(DayFormat)
+		return s.SendWeeklySummary, sameWeek
+	default:
+		return nil, false
+	}
+}
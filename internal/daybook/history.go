@@ -0,0 +1,104 @@
+package daybook
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Kinds of tasks in a stored daybook, describing where in the daybook the task appeared. Tasks in
+// project trees are kinded by their hierarchy level, so initiatives are stored as epics.
+const (
+	TaskKindEpic       = "epic"
+	TaskKindStory      = "story"
+	TaskKindSubtask    = "subtask"
+	TaskKindBug        = "bug"
+	TaskKindStandalone = "standalone"
+	TaskKindCreated    = "created"
+)
+
+// HistoryEntry is a daybook as stored in the history.
+type HistoryEntry struct {
+	SlackID     string
+	SlackHandle string
+	// Day is the user's local day the daybook covers, formatted with DayFormat.
+	Day         string
+	GeneratedAt time.Time
+	Tasks       []*HistoryTask
+}
+
+// HistoryTask is a task as it appeared in a stored daybook. The task tree can be rebuilt from the
+// tasks' parent IDs.
+type HistoryTask struct {
+	*Task
+
+	Kind string
+	// Section is the name of the status section the task was reported in, or empty for tasks that
+	// weren't reported in a section, such as created tasks.
+	Section string
+}
+
+// NewHistoryEntry flattens the daybook into a history entry.
+func NewHistoryEntry(db *Daybook, statuses *StatusModel) *HistoryEntry {
+	entry := &HistoryEntry{
+		SlackID:     db.User.SlackID,
+		SlackHandle: db.User.SlackHandle,
+		Day:         db.Day.Format(DayFormat),
+		GeneratedAt: time.Now(),
+	}
+
+	add := func(task *Task, kind, section string) {
+		entry.Tasks = append(entry.Tasks, &HistoryTask{Task: task, Kind: kind, Section: section})
+	}
+
+	for section, roots := range db.Projects {
+		for _, root := range roots {
+			root.Walk(func(node *TaskNode, _ int) {
+				add(node.Task, treeTaskKind(node.Task), section)
+			})
+		}
+	}
+
+	for section, bugs := range statuses.TasksBySection(db.Bugs) {
+		for _, bug := range bugs {
+			add(bug, TaskKindBug, section)
+		}
+	}
+
+	for section, tasks := range statuses.TasksBySection(db.StandaloneTasks) {
+		for _, task := range tasks {
+			add(task, TaskKindStandalone, section)
+		}
+	}
+
+	for _, task := range db.CreatedTasks {
+		add(task, TaskKindCreated, "")
+	}
+
+	return entry
+}
+
+// treeTaskKind returns the kind of a task in a project tree, based on its hierarchy level.
+func treeTaskKind(task *Task) string {
+	switch {
+	case task.Level >= LevelEpic:
+		return TaskKindEpic
+	case task.Level < LevelStory:
+		return TaskKindSubtask
+	default:
+		return TaskKindStory
+	}
+}
+
+// saveHistory stores the daybook in the history. Failing to store it is logged rather than
+// returned, so that history problems never keep a daybook from being sent.
+func (s *Service) saveHistory(ctx context.Context, db *Daybook) {
+	if s.history == nil {
+		return
+	}
+
+	err := s.history.SaveDaybook(ctx, NewHistoryEntry(db, s.cfg.Statuses))
+	if err != nil {
+		slog.Error("Saving daybook history", "User", db.User.SlackHandle, "Error", err)
+	}
+}
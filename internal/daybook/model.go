@@ -6,12 +6,23 @@ import (
 )
 
 type Daybook struct {
-	Day             time.Time
-	User            *User
-	Projects        map[string][]*Epic
+	Day  time.Time
+	User *User
+	// Projects holds the trees of the tasks worked on, such as epics and stories along with the
+	// tasks within them, keyed by the name of the status section they're reported in. Tasks without
+	// a parent or children are in StandaloneTasks instead.
+	Projects        map[string][]*TaskNode
 	Bugs            []*Task
 	CreatedTasks    []*Task
 	StandaloneTasks []*Task
+
+	// Changes are the transitions since the user's previous daybook, or nil if there is no
+	// previous daybook in the history.
+	Changes *Changes
+
+	// Warnings are shown alongside the daybook when it may be incomplete, such as when a search
+	// matched more tasks than could be fetched.
+	Warnings []string
 }
 
 type User struct {
@@ -19,47 +30,139 @@ type User struct {
 	SlackID         string
 	AtlassianID     string
 	DaybookChannels []string
+
+	// Timezone is the user's local time zone, which determines what "today" means for their
+	// daybook. UTC is used when it is nil.
+	Timezone *time.Location
+	// DaybookCrontab, ReminderCrontab and WeeklyCrontab override the default schedule for this
+	// user when set.
+	DaybookCrontab  string
+	ReminderCrontab string
+	WeeklyCrontab   string
+}
+
+// Location returns the user's time zone, defaulting to UTC.
+func (u *User) Location() *time.Location {
+	if u.Timezone == nil {
+		return time.UTC
+	}
+
+	return u.Timezone
+}
+
+// Window is a span of time that tasks are reported for.
+type Window struct {
+	Start time.Time
+	End   time.Time
 }
 
+// DayWindow returns the window from the start of the day of t, in t's location, until t.
+func DayWindow(t time.Time) Window {
+	year, month, day := t.Date()
+
+	return Window{
+		Start: time.Date(year, month, day, 0, 0, 0, 0, t.Location()),
+		End:   t,
+	}
+}
+
+// Contains reports whether t is within the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && !t.After(w.End)
+}
+
+// Levels of the standard issue types in the Jira issue hierarchy. Jira Premium adds levels above
+// epics, such as initiatives.
+const (
+	LevelSubtask = -1
+	LevelStory   = 0
+	LevelEpic    = 1
+)
+
 type Task struct {
-	Type         string
-	ID           string
-	Status       string
-	Link         *url.URL
-	Title        string
-	ParentTaskID string
+	Type           string
+	ID             string
+	Status         string
+	StatusCategory string
+	Link           *url.URL
+	Title          string
+	ParentTaskID   string
+	// Level is the task's level in the Jira issue hierarchy, such as LevelEpic. A task's parent is
+	// at a higher level than the task.
+	Level int
+
+	// StartedAt and CompletedAt are when the task last moved out of the To Do category and into
+	// the Done category. They're only known for the user's own tasks, and are zero otherwise.
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+// SearchResult holds the tasks returned by a search, which may be fewer than the number of tasks
+// that matched it.
+type SearchResult struct {
+	Tasks []*Task
+	// Total is the number of tasks that matched the search.
+	Total int
+}
+
+// Truncated reports whether tasks that matched the search were left out of the result.
+func (r *SearchResult) Truncated() bool {
+	return r.Total > len(r.Tasks)
+}
+
+// Emoji returns the name of the emoji shown next to the task, based on its type and level.
+func (t *Task) Emoji() string {
+	switch {
+	case t.Type == "Bug":
+		return "jira-bug"
+	case t.Level > LevelEpic:
+		return "jira-initiative"
+	case t.Level == LevelEpic:
+		return "jira-epic"
+	case t.Level < LevelStory:
+		return "jira-subtask"
+	case t.Type == "Story":
+		return "jira-story"
+	default:
+		return "jira-task"
+	}
 }
 
-type Epic struct {
+// TaskNode is a task in a tree organized by the Jira issue hierarchy, such as an initiative with
+// its epics, their stories, and the stories' subtasks.
+type TaskNode struct {
 	*Task
 
-	Stories []*Story
+	Children []*TaskNode
 }
 
-// ContainsTask checks if the epic contains a task with the given ID.
-// A task is considered to be contained in an epic if it is either a story or a subtask of a story
-// in the epic.
-func (e *Epic) ContainsTask(taskID string) bool {
-	for _, story := range e.Stories {
-		if story.ID == taskID {
-			return true
-		}
+// ContainsTask checks if the task with the given ID is the node's task or one of its descendants.
+func (n *TaskNode) ContainsTask(taskID string) bool {
+	if n.ID == taskID {
+		return true
+	}
 
-		for _, subtask := range story.Subtasks {
-			if subtask.ID == taskID {
-				return true
-			}
+	for _, child := range n.Children {
+		if child.ContainsTask(taskID) {
+			return true
 		}
 	}
 
 	return false
 }
 
-type Story struct {
-	*Task
+// Walk calls fn for the node and each of its descendants, parents before their children, along with
+// their depth below the node.
+func (n *TaskNode) Walk(fn func(node *TaskNode, depth int)) {
+	n.walk(fn, 0)
+}
 
-	Subtasks []*Task
-	Epic     *Epic
+func (n *TaskNode) walk(fn func(node *TaskNode, depth int), depth int) {
+	fn(n, depth)
+
+	for _, child := range n.Children {
+		child.walk(fn, depth+1)
+	}
 }
 
 type PullRequest struct {
@@ -0,0 +1,259 @@
+package daybook
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Report is a format-independent document of a daybook, weekly summary or team digest. Reports are
+// built here once, so that every notifier shows the same sections in the same order and only
+// translates the report to its own format.
+type Report struct {
+	// User is the user the report is about, mentioned before the title. It is nil for team digests.
+	User  *User
+	Title string
+
+	Sections []*ReportSection
+
+	// Warnings are shown after the sections when the report may be incomplete.
+	Warnings []string
+}
+
+// ReportSection is a heading of a report along with the items below it. Sections without items are
+// shown as a heading on its own, such as the number of tasks a team completed.
+type ReportSection struct {
+	Title string
+	// Emoji is the name of the emoji shown before the title. Optional.
+	Emoji string
+	Items []*ReportItem
+}
+
+// ReportItem is an entry of a report section, nested within its parent item.
+type ReportItem struct {
+	// Emoji is the name of the emoji shown before the item, such as "jira-story". Optional.
+	Emoji string
+	// Badge is a short label shown before the item, such as "Finished". Optional.
+	Badge string
+	// User is mentioned before the item's links, such as the team member working on the tasks.
+	// Optional.
+	User *User
+	// Links are the content of the item, separated by commas. A link without a URL is plain text.
+	Links []ReportLink
+	// Highlight marks items that should stand out, such as bugs.
+	Highlight bool
+
+	Children []*ReportItem
+}
+
+// Text returns the item as plain text, such as ":jira-story: Finished: Title". The children aren't
+// included.
+func (i *ReportItem) Text() string {
+	sb := strings.Builder{}
+
+	if i.Emoji != "" {
+		sb.WriteString(":" + i.Emoji + ": ")
+	}
+
+	if i.Badge != "" {
+		sb.WriteString(i.Badge + ": ")
+	}
+
+	if i.User != nil {
+		sb.WriteString("@" + i.User.SlackHandle + ": ")
+	}
+
+	texts := make([]string, 0, len(i.Links))
+	for _, link := range i.Links {
+		texts = append(texts, link.Text)
+	}
+	sb.WriteString(strings.Join(texts, ", "))
+
+	return sb.String()
+}
+
+// ReportLink is a piece of text, linking to the URL when it is set.
+type ReportLink struct {
+	Text string
+	URL  *url.URL
+}
+
+// NewDaybookReport lays out the daybook: the tasks of each status section, followed by the changes
+// since the previous daybook and the tasks the user created. Empty sections are left out.
+func NewDaybookReport(db *Daybook, statuses *StatusModel) *Report {
+	if statuses == nil {
+		statuses = DefaultStatusModel()
+	}
+
+	report := &Report{
+		User:     db.User,
+		Title:    "Daybook for " + db.Day.Format(DayFormat),
+		Warnings: db.Warnings,
+	}
+
+	bugs := statuses.TasksBySection(db.Bugs)
+	standalones := statuses.TasksBySection(db.StandaloneTasks)
+
+	for _, section := range statuses.Sections {
+		items := make([]*ReportItem, 0)
+		for _, bug := range bugs[section.Name] {
+			items = append(items, taskItem(bug))
+		}
+
+		for _, root := range db.Projects[section.Name] {
+			items = append(items, treeItem(root))
+		}
+
+		for _, task := range standalones[section.Name] {
+			items = append(items, taskItem(task))
+		}
+
+		if len(items) == 0 {
+			continue
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: section.Label, Emoji: section.Emoji, Items: items})
+	}
+
+	if db.Changes != nil && len(db.Changes.Tasks) > 0 {
+		items := make([]*ReportItem, 0, len(db.Changes.Tasks))
+		for _, change := range db.Changes.Tasks {
+			items = append(items, &ReportItem{Badge: change.Description(), Links: []ReportLink{taskLink(change.Task)}})
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: "Changes since " + db.Changes.Since, Items: items})
+	}
+
+	if len(db.CreatedTasks) > 0 {
+		items := make([]*ReportItem, 0, len(db.CreatedTasks))
+		for _, task := range db.CreatedTasks {
+			items = append(items, &ReportItem{Badge: "Created", Links: []ReportLink{taskLink(task)}})
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: "Planned Tasks", Emoji: "memo", Items: items})
+	}
+
+	return report
+}
+
+// NewWeeklyReport lays out the weekly summary: the completed tasks, the tasks still in progress, and
+// the number of tasks in each status.
+func NewWeeklyReport(summary *WeeklySummary) *Report {
+	report := &Report{
+		User:     summary.User,
+		Title:    "Week of " + summary.Week.Start.Format(DayFormat),
+		Warnings: summary.Warnings,
+	}
+
+	for _, group := range []struct {
+		title, emoji string
+		tasks        *TaskGroup
+	}{
+		{"Completed This Week", "white_check_mark", summary.Completed},
+		{"Still in Progress", "construction", summary.InProgress},
+	} {
+		if group.tasks.Count == 0 {
+			continue
+		}
+
+		items := make([]*ReportItem, 0)
+		for _, root := range group.tasks.Projects {
+			items = append(items, treeItem(root))
+		}
+
+		for _, task := range group.tasks.Tasks {
+			items = append(items, taskItem(task))
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{
+			Title: fmt.Sprintf("%s (%d)", group.title, group.tasks.Count),
+			Emoji: group.emoji,
+			Items: items,
+		})
+	}
+
+	if len(summary.StatusCounts) > 0 {
+		items := make([]*ReportItem, 0, len(summary.StatusCounts))
+		for _, count := range summary.StatusCounts {
+			items = append(items, &ReportItem{Links: []ReportLink{{Text: fmt.Sprintf("%s: %d", count.Status, count.Count)}}})
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: "Tasks by Status", Items: items})
+	}
+
+	return report
+}
+
+// NewTeamDigestReport lays out the team digest: the number of tasks completed, who is working on
+// what within each epic and outside of them, and the tasks stuck in review.
+func NewTeamDigestReport(digest *TeamDigest) *Report {
+	report := &Report{
+		Title:    fmt.Sprintf("%s Digest for %s", digest.Team.Name, digest.Day.Format(DayFormat)),
+		Warnings: digest.Warnings,
+	}
+
+	report.Sections = append(report.Sections, &ReportSection{
+		Title: fmt.Sprintf("%d tasks completed today", digest.Completed),
+		Emoji: "white_check_mark",
+	})
+
+	if len(digest.Epics) > 0 || len(digest.Other) > 0 {
+		items := make([]*ReportItem, 0)
+		for _, epic := range digest.Epics {
+			item := taskItem(epic.Task)
+			for _, member := range epic.Members {
+				item.Children = append(item.Children, memberItem(member))
+			}
+
+			items = append(items, item)
+		}
+
+		for _, member := range digest.Other {
+			items = append(items, memberItem(member))
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: "Working on", Items: items})
+	}
+
+	if len(digest.InReview) > 0 {
+		items := make([]*ReportItem, 0, len(digest.InReview))
+		for _, member := range digest.InReview {
+			items = append(items, memberItem(member))
+		}
+
+		report.Sections = append(report.Sections, &ReportSection{Title: "Stuck in Review", Emoji: "hourglass", Items: items})
+	}
+
+	return report
+}
+
+func taskLink(task *Task) ReportLink {
+	return ReportLink{Text: task.Title, URL: task.Link}
+}
+
+func taskItem(task *Task) *ReportItem {
+	return &ReportItem{
+		Emoji:     task.Emoji(),
+		Links:     []ReportLink{taskLink(task)},
+		Highlight: task.Type == "Bug",
+	}
+}
+
+// treeItem returns the item of the node, with the items of its descendants nested within it.
+func treeItem(node *TaskNode) *ReportItem {
+	item := taskItem(node.Task)
+	for _, child := range node.Children {
+		item.Children = append(item.Children, treeItem(child))
+	}
+
+	return item
+}
+
+func memberItem(member *MemberTasks) *ReportItem {
+	item := &ReportItem{User: member.User}
+	for _, task := range member.Tasks {
+		item.Links = append(item.Links, taskLink(task))
+	}
+
+	return item
+}
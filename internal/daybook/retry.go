@@ -0,0 +1,184 @@
+package daybook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// RetryableError marks an error as transient, such as a rate limit or a server error. RetryAfter is
+// the delay requested by the remote service, if any.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RetryableError) Retryable() bool {
+	return true
+}
+
+// retryable is implemented by errors that know whether they're transient, such as RetryableError
+// and the Slack client's errors.
+type retryable interface {
+	Retryable() bool
+}
+
+// RetryPolicy retries transient failures with exponential backoff.
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first one.
+	Attempts int
+	// InitialBackoff is the delay before the first retry, doubling on each retry up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:       4,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error that isn't transient, or runs out of attempts.
+// A delay requested by the remote service through a RetryableError is honored over the backoff.
+func (p RetryPolicy) Do(ctx context.Context, operation string, fn func(context.Context) error) error {
+	backoff := p.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= p.Attempts || !isRetryable(err) {
+			return err
+		}
+
+		delay := backoff
+		var retryableErr *RetryableError
+		if errors.As(err, &retryableErr) && retryableErr.RetryAfter > 0 {
+			delay = retryableErr.RetryAfter
+		}
+
+		slog.Warn("Retrying", "Operation", operation, "Attempt", attempt, "Delay", delay, "Error", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (retry cancelled: %w)", err, ctx.Err())
+		case <-time.After(delay):
+		}
+
+		backoff = min(backoff*2, p.MaxBackoff)
+	}
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var r retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+
+	// Connection failures and timeouts talking to the remote service
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryingNotifier wraps a Notifier, retrying sends that fail with a transient error.
+type RetryingNotifier struct {
+	Notifier
+
+	policy RetryPolicy
+}
+
+func NewRetryingNotifier(notifier Notifier, policy RetryPolicy) *RetryingNotifier {
+	return &RetryingNotifier{Notifier: notifier, policy: policy}
+}
+
+func (n *RetryingNotifier) SendDaybookEntry(ctx context.Context, daybook *Daybook) error {
+	return n.policy.Do(ctx, "SendDaybookEntry", func(ctx context.Context) error {
+		return n.Notifier.SendDaybookEntry(ctx, daybook)
+	})
+}
+
+func (n *RetryingNotifier) SendDaybookDMReminder(ctx context.Context, daybook *Daybook) error {
+	return n.policy.Do(ctx, "SendDaybookDMReminder", func(ctx context.Context) error {
+		return n.Notifier.SendDaybookDMReminder(ctx, daybook)
+	})
+}
+
+func (n *RetryingNotifier) SendWeeklySummary(ctx context.Context, summary *WeeklySummary) error {
+	return n.policy.Do(ctx, "SendWeeklySummary", func(ctx context.Context) error {
+		return n.Notifier.SendWeeklySummary(ctx, summary)
+	})
+}
+
+func (n *RetryingNotifier) SendTeamDigest(ctx context.Context, digest *TeamDigest) error {
+	return n.policy.Do(ctx, "SendTeamDigest", func(ctx context.Context) error {
+		return n.Notifier.SendTeamDigest(ctx, digest)
+	})
+}
+
+// RetryingTaskRepository wraps a TaskRepository, retrying lookups that fail with a transient error.
+type RetryingTaskRepository struct {
+	TaskRepository
+
+	policy RetryPolicy
+}
+
+func NewRetryingTaskRepository(tasks TaskRepository, policy RetryPolicy) *RetryingTaskRepository {
+	return &RetryingTaskRepository{TaskRepository: tasks, policy: policy}
+}
+
+func (r *RetryingTaskRepository) UserTasks(ctx context.Context, user *User, window Window) (*SearchResult, error) {
+	return retryValue(ctx, r.policy, "UserTasks", func(ctx context.Context) (*SearchResult, error) {
+		return r.TaskRepository.UserTasks(ctx, user, window)
+	})
+}
+
+func (r *RetryingTaskRepository) UserTasksOn(ctx context.Context, user *User, window Window) (*SearchResult, error) {
+	return retryValue(ctx, r.policy, "UserTasksOn", func(ctx context.Context) (*SearchResult, error) {
+		return r.TaskRepository.UserTasksOn(ctx, user, window)
+	})
+}
+
+func (r *RetryingTaskRepository) Task(ctx context.Context, taskID string) (*Task, error) {
+	return retryValue(ctx, r.policy, "Task", func(ctx context.Context) (*Task, error) {
+		return r.TaskRepository.Task(ctx, taskID)
+	})
+}
+
+func (r *RetryingTaskRepository) Tasks(ctx context.Context, taskIDs []string) ([]*Task, error) {
+	return retryValue(ctx, r.policy, "Tasks", func(ctx context.Context) ([]*Task, error) {
+		return r.TaskRepository.Tasks(ctx, taskIDs)
+	})
+}
+
+func (r *RetryingTaskRepository) CreatedByUser(ctx context.Context, user *User, window Window) (*SearchResult, error) {
+	return retryValue(ctx, r.policy, "CreatedByUser", func(ctx context.Context) (*SearchResult, error) {
+		return r.TaskRepository.CreatedByUser(ctx, user, window)
+	})
+}
+
+func retryValue[T any](ctx context.Context, policy RetryPolicy, operation string, fn func(context.Context) (T, error)) (T, error) {
+	var value T
+	err := policy.Do(ctx, operation, func(ctx context.Context) error {
+		var err error
+		value, err = fn(ctx)
+		return err
+	})
+
+	return value, err
+}
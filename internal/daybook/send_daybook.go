@@ -1,31 +1,52 @@
 package daybook
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 )
 
-func (s *Service) SendDaybookEntries(ctx context.Context, users []*User) error {
-	for _, userID := range users {
-		err := s.SendDaybookEntry(ctx, userID)
-		if err != nil {
-			slog.Error("Sending daybook entry", "UserID", userID, "Error", err)
-		}
-	}
+// SendDaybookEntries sends the daybook entry of every user, several users at a time. A failure for
+// one user doesn't stop the others, and is reported in the result and recorded as a dead letter.
+func (s *Service) SendDaybookEntries(ctx context.Context, users []*User) *BatchResult {
+	result := s.forEachUser(ctx, "Sending daybook entry", users, s.SendDaybookEntry)
+	s.recordDeadLetters(ctx, KindEntry, result)
 
-	return nil
+	return result
 }
 
 // SendDayBookEntry computes the daybook entry for the current day and sends it to the notifier
 func (s *Service) SendDaybookEntry(ctx context.Context, user *User) error {
+	return s.sendDaybookEntry(ctx, user, time.Now().In(user.Location()))
+}
+
+// SendDaybookEntriesOn sends every user's daybook for the date, reconstructed from the tasks'
+// history, in order to backfill missed days. Only the date of date is used, in each user's time
+// zone. Failures are recorded as dead letters for that day, so replaying them resends the same day.
+func (s *Service) SendDaybookEntriesOn(ctx context.Context, users []*User, date time.Time) *BatchResult {
+	result := s.forEachUser(ctx, "Sending past daybook entry", users, func(ctx context.Context, user *User) error {
+		return s.sendDaybookEntry(ctx, user, date)
+	})
+
+	year, month, day := date.Date()
+	s.recordDeadLettersOn(ctx, KindEntry, result, func(user *User) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, user.Location())
+	})
+
+	return result
+}
+
+func (s *Service) sendDaybookEntry(ctx context.Context, user *User, date time.Time) error {
 	color.White("Sending daybook entry for @%s", user.SlackHandle)
 
 	// Generate the daybook entry
-	daybook, err := s.generateDaybookEntry(ctx, user)
+	daybook, err := s.generateDaybookEntry(ctx, user, date)
 	if err != nil {
 		return fmt.Errorf("generating daybook entry: %w", err)
 	}
@@ -36,25 +57,28 @@ func (s *Service) SendDaybookEntry(ctx context.Context, user *User) error {
 		return fmt.Errorf("sending daybook entry: %w", err)
 	}
 
+	// Only the daybooks that were sent are stored, so that previews and digests generated in
+	// between never replace what was posted
+	s.saveHistory(ctx, daybook)
+
 	return nil
 }
 
-func (s *Service) SendDaybookDMReminders(ctx context.Context, users []*User) error {
-	for _, user := range users {
-		err := s.SendDaybookDMReminder(ctx, user)
-		if err != nil {
-			slog.Error("Sending daybook DM reminder", "UserID", user.SlackHandle, "Error", err)
-		}
-	}
+// SendDaybookDMReminders sends the daybook DM reminder of every user, several users at a time. A
+// failure for one user doesn't stop the others, and is reported in the result and recorded as a
+// dead letter.
+func (s *Service) SendDaybookDMReminders(ctx context.Context, users []*User) *BatchResult {
+	result := s.forEachUser(ctx, "Sending daybook DM reminder", users, s.SendDaybookDMReminder)
+	s.recordDeadLetters(ctx, KindReminder, result)
 
-	return nil
+	return result
 }
 
 func (s *Service) SendDaybookDMReminder(ctx context.Context, user *User) error {
 	color.White("Sending daybook DM reminder for @%s", user.SlackHandle)
 
 	// Generate the daybook entry
-	daybook, err := s.generateDaybookEntry(ctx, user)
+	daybook, err := s.generateDaybookEntry(ctx, user, time.Now().In(user.Location()))
 	if err != nil {
 		return fmt.Errorf("generating daybook entry: %w", err)
 	}
@@ -68,16 +92,45 @@ func (s *Service) SendDaybookDMReminder(ctx context.Context, user *User) error {
 	return nil
 }
 
-func (s *Service) generateDaybookEntry(ctx context.Context, user *User) (*Daybook, error) {
-	daybook := &Daybook{Day: time.Now(), User: user}
+// generateDaybookEntry generates the user's daybook for the date. Only the date of date is used, in
+// the user's time zone. Today's daybook reflects the current state of Jira, while a past day's is
+// reconstructed from the tasks' history as of the end of that day.
+func (s *Service) generateDaybookEntry(ctx context.Context, user *User, date time.Time) (*Daybook, error) {
+	// The daybook covers the user's local day, so that users in other time zones report on the
+	// same working day their teammates see.
+	loc := user.Location()
+	now := time.Now().In(loc)
+
+	daybook := &Daybook{Day: now, User: user}
+	window := DayWindow(now)
+	userTasks := s.tasks.UserTasks
+
+	year, month, day := date.Date()
+	if y, m, d := now.Date(); year != y || month != m || day != d {
+		start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		if start.After(now) {
+			return nil, fmt.Errorf("%s is in the future", start.Format(DayFormat))
+		}
+
+		daybook.Day = start
+		window = Window{Start: start, End: start.AddDate(0, 0, 1)}
+		userTasks = s.tasks.UserTasksOn
+	}
 
 	// Get all the tasks assigned to the user
-	tasks, err := s.tasks.UserTasks(ctx, user)
+	result, err := userTasks(ctx, user, window)
 	if err != nil {
 		return nil, fmt.Errorf("getting user tasks: %w", err)
 	}
 
-	color.Green("User has %d assigned tasks", len(tasks))
+	// The search matches recent tasks on any update, so check that they were actually completed
+	// or started today
+	tasks := s.cfg.Statuses.RecentTasks(result.Tasks, window)
+	if result.Truncated() {
+		daybook.Warnings = append(daybook.Warnings, fmt.Sprintf("Only %d of %d assigned tasks could be fetched, so some work may be missing.", len(result.Tasks), result.Total))
+	}
+
+	color.Green("@%s has %d assigned tasks", user.SlackHandle, len(tasks))
 
 	// Organize the tasks into the daybook entry
 
@@ -96,28 +149,45 @@ func (s *Service) generateDaybookEntry(ctx context.Context, user *User) (*Dayboo
 		return nil, fmt.Errorf("populating standalone tasks: %w", err)
 	}
 
+	err = s.populatePlannedTasks(ctx, daybook, window)
+	if err != nil {
+		return nil, fmt.Errorf("populating planned tasks: %w", err)
+	}
+
+	s.populateChanges(ctx, daybook)
+
 	return daybook, nil
 }
 
 // populateProjects takes a set of tasks and populates the Projects field of the daybook with the tasks
-// organized by project. This is done by creating a tree of Epics, Stories, and Subtasks.
+// organized by project. This is done by creating a tree of the tasks and their parents, following
+// the Jira issue hierarchy.
 func (s *Service) populateProjects(ctx context.Context, daybook *Daybook, tasks []*Task) error {
-	// Group the tasks by status
-	byStatus := make(map[string][]*Task)
-	for _, task := range tasks {
-		byStatus[task.Status] = append(byStatus[task.Status], task)
-	}
-
-	// Get the task tree for each status, which places it in the correct Epic for
-	// per-project reporting
-	projectTasks := make(map[string][]*Epic)
-	for status, tasks := range byStatus {
-		tree, err := s.organizeEpics(ctx, tasks)
+	// Bugs are reported on their own
+	tasks = slices.DeleteFunc(slices.Clone(tasks), func(task *Task) bool {
+		return task.Type == "Bug"
+	})
+
+	// Group the tasks by the status section they're reported in
+	bySection := s.cfg.Statuses.TasksBySection(tasks)
+
+	// Get the task tree for each section, which places it within its epic, and any initiative
+	// above it, for per-project reporting
+	projectTasks := make(map[string][]*TaskNode)
+	for section, tasks := range bySection {
+		roots, err := s.organizeTasks(ctx, tasks)
 		if err != nil {
 			return fmt.Errorf("getting task tree: %w", err)
 		}
 
-		projectTasks[status] = tree
+		// Tasks without a parent or children are reported as standalone tasks
+		roots = slices.DeleteFunc(roots, func(root *TaskNode) bool {
+			return len(root.Children) == 0
+		})
+
+		if len(roots) > 0 {
+			projectTasks[section] = roots
+		}
 	}
 
 	daybook.Projects = projectTasks
@@ -125,56 +195,113 @@ func (s *Service) populateProjects(ctx context.Context, daybook *Daybook, tasks
 	return nil
 }
 
-// organizeEpics takes a set of tasks and organizes them into a tree of Epics, Stories, and Subtasks.
-// Tasks that are not within an Epic are ignored and not included in the output.
-func (s *Service) organizeEpics(ctx context.Context, tasks []*Task) ([]*Epic, error) {
-	// First, get the set of stories from the tasks
-	stories := make(map[string]*Story)
+// maxHierarchyDepth bounds how far organizeTasks walks up the hierarchy, guarding against parent
+// cycles.
+const maxHierarchyDepth = 10
+
+// organizeTasks takes a set of tasks and organizes them into trees following the Jira issue
+// hierarchy, returning their roots. Parents that aren't among the tasks are fetched and included
+// for context, up to the top of the hierarchy. The hierarchy is walked one level at a time, so each
+// level is fetched in a single batch no matter how many tasks share it. Tasks whose parent can't be
+// fetched, such as a deleted parent or one the bot can't see, are roots of their own.
+func (s *Service) organizeTasks(ctx context.Context, tasks []*Task) ([]*TaskNode, error) {
+	orphans := make(map[string]bool)
+	nodes := make(map[string]*TaskNode)
+	pending := make([]*TaskNode, 0, len(tasks))
 	for _, task := range tasks {
-		if task.Type == "Story" {
-			stories[task.ID] = &Story{Task: task}
-		}
+		nodes[task.ID] = &TaskNode{Task: task}
+		pending = append(pending, nodes[task.ID])
 	}
 
-	// Next, put all the subtasks into their parent stories
-	for _, task := range tasks {
-		if task.Type == "Sub-task" {
-			slog.Info("Getting parent task for subtask", "Subtask", task.ID)
-			parent, err := s.tasks.Task(ctx, task.ParentTaskID)
-			if err != nil {
-				return nil, fmt.Errorf("getting parent task: %w", err)
+	for depth := 0; len(pending) > 0; depth++ {
+		if depth > maxHierarchyDepth {
+			return nil, fmt.Errorf("task hierarchy deeper than %d levels", maxHierarchyDepth)
+		}
+
+		parentIDs := make([]string, 0)
+		for _, node := range pending {
+			if _, ok := nodes[node.ParentTaskID]; node.ParentTaskID != "" && !ok {
+				parentIDs = append(parentIDs, node.ParentTaskID)
+			}
+		}
+
+		parents, err := s.taskMap(ctx, parentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("getting parent tasks: %w", err)
+		}
+
+		// The parents fetched for this level are placed within their own parents on the next one
+		next := make([]*TaskNode, 0)
+		for _, node := range pending {
+			if node.ParentTaskID == "" {
+				continue
 			}
 
-			if _, ok := stories[parent.ID]; !ok {
-				stories[parent.ID] = &Story{Task: parent, Subtasks: []*Task{}}
+			parent, ok := nodes[node.ParentTaskID]
+			if !ok {
+				task, ok := parents[node.ParentTaskID]
+				if !ok {
+					slog.Warn("Parent task not found", "Task", node.ID, "Parent", node.ParentTaskID)
+					orphans[node.ID] = true
+					continue
+				}
+
+				parent = &TaskNode{Task: task}
+				nodes[task.ID] = parent
+				next = append(next, parent)
 			}
 
-			stories[parent.ID].Subtasks = append(stories[parent.ID].Subtasks, task)
+			parent.Children = append(parent.Children, node)
 		}
+
+		pending = next
 	}
 
-	// Get the set of epics from the stories
-	epics := make(map[string]*Epic)
-	for _, story := range stories {
-		epic, err := s.tasks.RootTask(ctx, story.ID)
-		if err != nil {
-			return nil, fmt.Errorf("getting root task: %w", err)
+	roots := make([]*TaskNode, 0)
+	for _, node := range nodes {
+		if node.ParentTaskID == "" || orphans[node.ID] {
+			roots = append(roots, node)
 		}
+	}
 
-		if _, ok := epics[epic.ID]; !ok {
-			epics[epic.ID] = &Epic{Task: epic, Stories: []*Story{}}
-		}
+	sortTaskNodes(roots)
+
+	return roots, nil
+}
+
+// sortTaskNodes sorts the nodes and their descendants by level, highest first, and then by ID.
+func sortTaskNodes(nodes []*TaskNode) {
+	slices.SortFunc(nodes, func(a, b *TaskNode) int {
+		return cmp.Or(cmp.Compare(b.Level, a.Level), cmp.Compare(a.ID, b.ID))
+	})
 
-		epics[epic.ID].Stories = append(epics[epic.ID].Stories, story)
+	for _, node := range nodes {
+		sortTaskNodes(node.Children)
+	}
+}
+
+// taskMap fetches the tasks with the given IDs in a single batch, keyed by ID.
+func (s *Service) taskMap(ctx context.Context, taskIDs []string) (map[string]*Task, error) {
+	byID := make(map[string]*Task)
+	if len(taskIDs) == 0 {
+		return byID, nil
+	}
+
+	slog.Info("Getting tasks", "TaskIDs", taskIDs)
+
+	ids := slices.Clone(taskIDs)
+	slices.Sort(ids)
+
+	tasks, err := s.tasks.Tasks(ctx, slices.Compact(ids))
+	if err != nil {
+		return nil, fmt.Errorf("getting tasks: %w", err)
 	}
 
-	// Convert the map to a slice
-	ee := make([]*Epic, 0, len(epics))
-	for _, epic := range epics {
-		ee = append(ee, epic)
+	for _, task := range tasks {
+		byID[task.ID] = task
 	}
 
-	return ee, nil
+	return byID, nil
 }
 
 // populateBugs takes a set of tasks and populates the Bugs field of the daybook with the tasks
@@ -194,13 +321,18 @@ func (s *Service) populateBugs(_ context.Context, daybook *Daybook, tasks []*Tas
 }
 
 func (s *Service) populateStandaloneTasks(_ context.Context, daybook *Daybook, tasks []*Task) error {
-	// Get the tasks without an associated project, which capture non-project work
+	// Get the tasks without an associated project, which capture non-project work. Bugs are
+	// reported on their own.
 	standaloneTasks := make([]*Task, 0)
 	for _, task := range tasks {
+		if task.Type == "Bug" {
+			continue
+		}
+
 		inProject := false
-		for _, tree := range daybook.Projects {
-			for _, epic := range tree {
-				if epic.ContainsTask(task.ID) {
+		for _, roots := range daybook.Projects {
+			for _, root := range roots {
+				if root.ContainsTask(task.ID) {
 					inProject = true
 					break
 				}
@@ -217,27 +349,27 @@ func (s *Service) populateStandaloneTasks(_ context.Context, daybook *Daybook, t
 	return nil
 }
 
-func (s *Service) populatePlannedTasks(ctx context.Context, daybook *Daybook) error {
-	// Get the tasks created by the user today, since it indicates planning work
-	createdTasks, err := s.tasks.CreatedByUser(ctx)
+func (s *Service) populatePlannedTasks(ctx context.Context, daybook *Daybook, window Window) error {
+	// Get the tasks created by the user during the day, since it indicates planning work
+	result, err := s.tasks.CreatedByUser(ctx, daybook.User, window)
 	if err != nil {
 		return fmt.Errorf("getting tasks created by user: %w", err)
 	}
 
-	daybook.CreatedTasks = createdTasks
+	if result.Truncated() {
+		daybook.Warnings = append(daybook.Warnings, fmt.Sprintf("Only %d of %d created tasks could be fetched.", len(result.Tasks), result.Total))
+	}
+
+	daybook.CreatedTasks = result.Tasks
 
 	return nil
 }
 
-func (s *Service) printEpics(title string, epics []*Epic) {
+func (s *Service) printTaskTree(title string, roots []*TaskNode) {
 	color.White(title)
-	for _, epic := range epics {
-		color.White("  Epic: %s", epic.Title)
-		for _, story := range epic.Stories {
-			color.White("    Story: %s", story.Title)
-			for _, subtask := range story.Subtasks {
-				color.White("      Subtask: %s", subtask.Title)
-			}
-		}
+	for _, root := range roots {
+		root.Walk(func(node *TaskNode, depth int) {
+			color.White("%s%s: %s", strings.Repeat("  ", depth+1), node.Type, node.Title)
+		})
 	}
 }
@@ -0,0 +1,58 @@
+package daybook
+
+import (
+	"context"
+	"time"
+)
+
+// DayFormat is the format of the day keys used to identify a user's daybook for a given day.
+const DayFormat = "2006-01-02"
+
+// Kinds of sent messages.
+const (
+	SentKindDaybook       = "daybook"
+	SentKindWeeklySummary = "weekly_summary"
+	SentKindTeamDigest    = "team_digest"
+)
+
+// SentMessage records a daybook, weekly summary or team digest posted to a channel, so that the same
+// message is never posted to the channel twice. Sending it again updates the posted message instead.
+type SentMessage struct {
+	// Kind is one of the SentKind constants. Records without a kind are daybooks.
+	Kind string `json:"kind,omitempty"`
+	// SlackID is the user the message is about. It is empty for team digests.
+	SlackID string `json:"slack_id"`
+	// Team is the name of the team of a team digest.
+	Team string `json:"team,omitempty"`
+	// Day is the local day the message covers, formatted with DayFormat. For weekly summaries, it is
+	// the first day of the week.
+	Day     string `json:"day"`
+	Channel string `json:"channel"`
+	// HeaderTS is the timestamp of a daybook thread's header message, and MessageTS the timestamp of
+	// the message itself. For daybooks, MessageTS is empty if posting the daybook failed after the
+	// header was posted.
+	HeaderTS  string `json:"header_ts,omitempty"`
+	MessageTS string `json:"message_ts"`
+	// SentAt is when the message was last posted or updated.
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Owner returns who the message is about: the user's Slack ID, or the team's name for team digests.
+func (m *SentMessage) Owner() string {
+	if m.Kind == SentKindTeamDigest {
+		return m.Team
+	}
+
+	return m.SlackID
+}
+
+// SentStore remembers which messages have been posted where.
+type SentStore interface {
+	// SentMessage returns the record of the message of the kind for the owner and day in the channel,
+	// or nil if it hasn't been posted there. The owner is a user's Slack ID, or a team's name for
+	// team digests.
+	SentMessage(ctx context.Context, kind, owner, day, channel string) (*SentMessage, error)
+	// RecordSentMessage stores the record, replacing any previous record for the same kind, owner,
+	// day and channel.
+	RecordSentMessage(ctx context.Context, msg *SentMessage) error
+}
@@ -2,33 +2,81 @@ package daybook
 
 import (
 	"context"
+	"time"
 )
 
 type Notifier interface {
 	SendDaybookEntry(ctx context.Context, daybook *Daybook) error
 	SendDaybookDMReminder(ctx context.Context, daybook *Daybook) error
+	SendWeeklySummary(ctx context.Context, summary *WeeklySummary) error
+	SendTeamDigest(ctx context.Context, digest *TeamDigest) error
 }
 
 type TaskRepository interface {
-	UserTasks(ctx context.Context, user *User) ([]*Task, error)
+	UserTasks(ctx context.Context, user *User, window Window) (*SearchResult, error)
+	// UserTasksOn returns the tasks that UserTasks would have returned at the end of the past
+	// window, with their statuses as they were then.
+	UserTasksOn(ctx context.Context, user *User, window Window) (*SearchResult, error)
 	Task(ctx context.Context, taskID string) (*Task, error)
-	RootTask(ctx context.Context, taskID string) (*Task, error)
-	CreatedByUser(ctx context.Context) ([]*Task, error)
+	// Tasks returns the tasks with the given IDs in a single request. Tasks that don't exist are
+	// left out of the result.
+	Tasks(ctx context.Context, taskIDs []string) ([]*Task, error)
+	// CreatedByUser returns the tasks the user reported within the window, which indicate planning
+	// work.
+	CreatedByUser(ctx context.Context, user *User, window Window) (*SearchResult, error)
+}
+
+// DaybookStore persists generated daybooks, so that they can be compared across days and used for
+// reporting.
+type DaybookStore interface {
+	SaveDaybook(ctx context.Context, entry *HistoryEntry) error
+	// Daybook returns the user's stored daybook for the day, or nil if there isn't one.
+	Daybook(ctx context.Context, slackID, day string) (*HistoryEntry, error)
+	// PreviousDaybook returns the user's latest stored daybook before the day, or nil if there isn't
+	// one.
+	PreviousDaybook(ctx context.Context, slackID, day string) (*HistoryEntry, error)
 }
 
 type Config struct {
+	// Statuses maps Jira statuses to daybook sections. DefaultStatusModel is used when nil.
+	Statuses *StatusModel
+	// Concurrency is the number of users processed at once by batch sends. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+	// UserTimeout bounds the time spent on a single user within a batch send. Defaults to
+	// DefaultUserTimeout.
+	UserTimeout time.Duration
+	// Output names the notifier's output, such as "slack". It is recorded in dead letters, so that
+	// they're only replayed through the output they failed on.
+	Output string
 }
 
 type Service struct {
-	cfg      Config
-	notifier Notifier
-	tasks    TaskRepository
+	cfg         Config
+	notifier    Notifier
+	tasks       TaskRepository
+	deadLetters DeadLetterStore
+	history     DaybookStore
 }
 
-func NewService(cfg Config, notifier Notifier, tasks TaskRepository) *Service {
+// NewService creates the daybook service. deadLetters may be nil, in which case failed sends are
+// only logged, and history may be nil, in which case generated daybooks aren't stored.
+func NewService(cfg Config, notifier Notifier, tasks TaskRepository, deadLetters DeadLetterStore, history DaybookStore) *Service {
+	if cfg.Statuses == nil {
+		cfg.Statuses = DefaultStatusModel()
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.UserTimeout <= 0 {
+		cfg.UserTimeout = DefaultUserTimeout
+	}
+
 	return &Service{
-		cfg:      cfg,
-		notifier: notifier,
-		tasks:    tasks,
+		cfg:         cfg,
+		notifier:    notifier,
+		tasks:       tasks,
+		deadLetters: deadLetters,
+		history:     history,
 	}
 }
@@ -0,0 +1,139 @@
+package daybook
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Jira status categories, which every Jira status belongs to regardless of the workflow.
+const (
+	CategoryToDo       = "To Do"
+	CategoryInProgress = "In Progress"
+	CategoryDone       = "Done"
+)
+
+// StatusSection is a section of the daybook, grouping tasks by their Jira status.
+type StatusSection struct {
+	// Name identifies the section, and is the key tasks are grouped under in the Daybook.
+	Name string
+	// Label is the heading the section is reported under.
+	Label string
+	// Emoji is an optional Slack emoji name shown next to the label, without colons.
+	Emoji string
+	// Statuses are the Jira statuses reported in this section.
+	Statuses []string
+	// Category is an optional Jira status category. Statuses in the category that aren't mapped to
+	// any section are reported in this section.
+	Category string
+	// Recent sections only report tasks updated within the daybook's window, such as tasks that
+	// were completed today.
+	Recent bool
+	// Review sections hold tasks waiting on someone else, such as code review. Team digests point
+	// out the tasks that are stuck in them.
+	Review bool
+}
+
+// EnteredAt returns when the task moved into the section's status category, such as when it was
+// completed for a section of done tasks, or the zero time if that isn't known. Sections without a
+// category use the category of the task's status.
+func (s *StatusSection) EnteredAt(task *Task) time.Time {
+	switch cmp.Or(s.Category, task.StatusCategory) {
+	case CategoryDone:
+		return task.CompletedAt
+	case CategoryInProgress:
+		return task.StartedAt
+	default:
+		return time.Time{}
+	}
+}
+
+// StatusModel maps Jira statuses to the sections of the daybook.
+type StatusModel struct {
+	// Sections are reported in order.
+	Sections []*StatusSection
+	// Excluded statuses are never reported, even if their category maps to a section.
+	Excluded []string
+}
+
+// DefaultStatusModel returns the status model for the default Jira software workflow.
+func DefaultStatusModel() *StatusModel {
+	return &StatusModel{
+		Sections: []*StatusSection{
+			{Name: "Done", Label: "Completed Today", Statuses: []string{"Done"}, Category: CategoryDone, Recent: true},
+			{Name: "In Progress", Label: "Working on", Statuses: []string{"In Progress"}, Category: CategoryInProgress},
+			{Name: "Code Review", Label: "In Code Review", Statuses: []string{"Code Review"}, Review: true},
+			{Name: "Testing", Label: "Testing", Statuses: []string{"Testing"}},
+		},
+	}
+}
+
+// Section returns the section the task is reported in. Tasks are matched on their status first,
+// falling back to their status category.
+func (m *StatusModel) Section(task *Task) (*StatusSection, bool) {
+	if slices.Contains(m.Excluded, task.Status) {
+		return nil, false
+	}
+
+	for _, section := range m.Sections {
+		if slices.Contains(section.Statuses, task.Status) {
+			return section, true
+		}
+	}
+
+	for _, section := range m.Sections {
+		if section.Category != "" && section.Category == task.StatusCategory {
+			return section, true
+		}
+	}
+
+	return nil, false
+}
+
+// TasksBySection groups the tasks by the name of the section they're reported in. Tasks that
+// aren't reported in any section are left out.
+func (m *StatusModel) TasksBySection(tasks []*Task) map[string][]*Task {
+	bySection := make(map[string][]*Task)
+	for _, task := range tasks {
+		section, ok := m.Section(task)
+		if !ok {
+			continue
+		}
+
+		bySection[section.Name] = append(bySection[section.Name], task)
+	}
+
+	return bySection
+}
+
+// Completed reports whether the task is done, by its status category or by the category of the
+// section it's reported in.
+func (m *StatusModel) Completed(task *Task) bool {
+	section, ok := m.Section(task)
+
+	return isDone(task, section, ok)
+}
+
+func isDone(task *Task, section *StatusSection, reported bool) bool {
+	return task.StatusCategory == CategoryDone || (reported && section.Category == CategoryDone)
+}
+
+// RecentTasks leaves out the tasks reported in recent sections that didn't move into the section's
+// status category within the window, such as old tasks that were only commented on. Tasks whose
+// transition time isn't known are kept.
+func (m *StatusModel) RecentTasks(tasks []*Task, window Window) []*Task {
+	recent := make([]*Task, 0, len(tasks))
+	for _, task := range tasks {
+		section, ok := m.Section(task)
+		if ok && section.Recent {
+			at := section.EnteredAt(task)
+			if !at.IsZero() && !window.Contains(at) {
+				continue
+			}
+		}
+
+		recent = append(recent, task)
+	}
+
+	return recent
+}
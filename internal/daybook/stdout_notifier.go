@@ -1,131 +1,125 @@
 package daybook
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"strings"
+	"sync"
 
 	"github.com/fatih/color"
 )
 
+// StdoutNotifier prints what would be sent to the terminal. Each message is rendered in full before
+// it is printed, so that the messages of users sent at once don't interleave.
 type StdoutNotifier struct {
+	// Statuses determines the sections the daybook is printed in. DefaultStatusModel is used when nil.
+	Statuses *StatusModel
+	// Template renders daybook entries. They're printed like weekly summaries and team digests when
+	// it is nil.
+	Template *DaybookTemplate
+
+	// mu keeps the messages of users sent at once from interleaving
+	mu sync.Mutex
 }
 
 const indentAmount = 4
 
-func (s *StdoutNotifier) SendDaybookEntry(ctx context.Context, db *Daybook) error {
-	color.White("Would send daybook entry")
-
-	color.White("@%s's Daybook for %s", db.User.SlackHandle, db.Day.Format("2006-01-02"))
-
-	statusUpdates := map[string]string{
-		"In Progress": "Working on",
-		"Code Review": "In Code Review",
-		"Testing":     "Testing",
-		"Done":        "Completed Today",
-	}
-
-	order := []string{"Done", "In Progress", "Code Review", "Testing"}
-
-	bugs := TasksByStatus(db.Bugs)
-	standalones := TasksByStatus(db.StandaloneTasks)
-
-	for _, status := range order {
-		epics := db.Projects[status]
-		bb := bugs[status]
-		standalone := standalones[status]
-
-		taskCount := len(epics) + len(bb) + len(standalone)
-
-		if taskCount == 0 {
-			continue
-		}
-
-		color.Green(statusUpdates[status])
-
-		for _, bug := range bb {
-			color.Yellow(s.formatBugReport(bug, indentAmount))
-		}
-
-		for _, epic := range epics {
-			color.White(s.formatEpicReport(epic, indentAmount))
-		}
+var (
+	white  = color.New(color.FgWhite)
+	green  = color.New(color.FgGreen)
+	yellow = color.New(color.FgYellow)
+)
 
-		for _, task := range standalone {
-			color.White(s.formatTaskReport(task, indentAmount))
-		}
-	}
+func (s *StdoutNotifier) SendDaybookEntry(ctx context.Context, db *Daybook) error {
+	buf := &bytes.Buffer{}
+	white.Fprintln(buf, "Would send daybook entry")
 
-	if len(db.CreatedTasks) > 0 {
-		color.Green("Planned Tasks")
-		for _, task := range db.CreatedTasks {
-			color.White(s.formatPlannedTask(task, indentAmount))
+	if s.Template != nil {
+		err := s.Template.Execute(buf, db)
+		if err != nil {
+			return err
 		}
+	} else {
+		s.printReport(buf, NewDaybookReport(db, s.Statuses))
 	}
 
-	return nil
+	return s.write(buf)
 }
 
 func (s *StdoutNotifier) SendDaybookDMReminder(ctx context.Context, daybook *Daybook) error {
-	color.White("Would send daybook DM reminder for @%s", daybook.User.SlackHandle)
+	buf := &bytes.Buffer{}
+	white.Fprintf(buf, "Would send daybook DM reminder for @%s\n", daybook.User.SlackHandle)
 
-	return nil
+	return s.write(buf)
 }
 
-func (s *StdoutNotifier) formatBugReport(bug *Task, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- :jira-bug: " + bug.Title)
+func (s *StdoutNotifier) SendWeeklySummary(ctx context.Context, summary *WeeklySummary) error {
+	buf := &bytes.Buffer{}
+	white.Fprintln(buf, "Would send weekly summary")
+
+	s.printReport(buf, NewWeeklyReport(summary))
 
-	return sb.String()
+	return s.write(buf)
 }
 
-func (s *StdoutNotifier) formatEpicReport(epic *Epic, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- :jira-epic: " + epic.Title)
-	sb.WriteString("\n")
+func (s *StdoutNotifier) SendTeamDigest(ctx context.Context, digest *TeamDigest) error {
+	buf := &bytes.Buffer{}
+	white.Fprintf(buf, "Would send team digest to %s\n", digest.Team.Channel)
 
-	for _, story := range epic.Stories {
-		sb.WriteString(s.formatStoryReport(story, indent+indentAmount))
-	}
+	s.printReport(buf, NewTeamDigestReport(digest))
 
-	return sb.String()
+	return s.write(buf)
 }
 
-func (s *StdoutNotifier) formatStoryReport(story *Story, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- :jira-story: " + story.Title)
-	sb.WriteString("\n")
+// write prints the rendered message at once.
+func (s *StdoutNotifier) write(buf *bytes.Buffer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for _, subtask := range story.Subtasks {
-		sb.WriteString(s.formatSubtaskReport(subtask, indent+indentAmount))
-		sb.WriteString("\n")
+	_, err := buf.WriteTo(color.Output)
+	if err != nil {
+		return fmt.Errorf("writing to stdout: %w", err)
 	}
 
-	return sb.String()
+	return nil
 }
 
-func (s *StdoutNotifier) formatTaskReport(task *Task, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- :jira-task: " + task.Title)
+func (s *StdoutNotifier) printReport(w io.Writer, report *Report) {
+	if report.User != nil {
+		white.Fprintf(w, "@%s's %s\n", report.User.SlackHandle, report.Title)
+	} else {
+		white.Fprintln(w, report.Title)
+	}
 
-	return sb.String()
-}
+	for _, section := range report.Sections {
+		if section.Emoji != "" {
+			green.Fprintf(w, ":%s: %s\n", section.Emoji, section.Title)
+		} else {
+			green.Fprintln(w, section.Title)
+		}
 
-func (s *StdoutNotifier) formatSubtaskReport(task *Task, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- :jira-subtask: " + task.Title)
+		for _, item := range section.Items {
+			s.printItem(w, item, indentAmount)
+		}
+	}
 
-	return sb.String()
+	for _, warning := range report.Warnings {
+		yellow.Fprintf(w, "Warning: %s\n", warning)
+	}
 }
 
-func (s *StdoutNotifier) formatPlannedTask(task *Task, indent int) string {
-	sb := strings.Builder{}
-	sb.WriteString(strings.Repeat(" ", indent))
-	sb.WriteString("- [Created]" + task.Title)
+// printItem prints the item and its children, each indented below its parent.
+func (s *StdoutNotifier) printItem(w io.Writer, item *ReportItem, indent int) {
+	line := strings.Repeat(" ", indent) + "- " + item.Text()
+	if item.Highlight {
+		yellow.Fprintln(w, line)
+	} else {
+		white.Fprintln(w, line)
+	}
 
-	return sb.String()
+	for _, child := range item.Children {
+		s.printItem(w, child, indent+indentAmount)
+	}
 }
@@ -0,0 +1,166 @@
+package daybook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// fetchTimeout bounds the shared requests of the cache, which don't end when the lookup that started
+// them is canceled, since other lookups may be waiting on them.
+const fetchTimeout = time.Minute
+
+// CachedTaskRepository wraps a TaskRepository, caching the tasks looked up by ID. Concurrent
+// lookups of the same task share a single request. Searches are not cached, since they reflect
+// the current state of each user's work.
+type CachedTaskRepository struct {
+	TaskRepository
+
+	ttl time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*taskCall
+	// swept is when expired entries were last removed.
+	swept time.Time
+}
+
+type cacheEntry struct {
+	task    *Task
+	expires time.Time
+}
+
+// taskCall is an in-flight lookup of a task, which other lookups of the same task wait on.
+type taskCall struct {
+	done chan struct{}
+	task *Task
+	err  error
+}
+
+func NewCachedTaskRepository(tasks TaskRepository, ttl time.Duration) *CachedTaskRepository {
+	return &CachedTaskRepository{
+		TaskRepository: tasks,
+		ttl:            ttl,
+		entries:        make(map[string]cacheEntry),
+		inflight:       make(map[string]*taskCall),
+	}
+}
+
+func (c *CachedTaskRepository) Task(ctx context.Context, taskID string) (*Task, error) {
+	tasks, err := c.Tasks(ctx, []string{taskID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("task %s not found", taskID)
+	}
+
+	return tasks[0], nil
+}
+
+// Tasks returns the cached tasks, fetching the ones that aren't cached in a single batch. The batch
+// is fetched in the background, so that a canceled lookup returns right away without failing the
+// other lookups waiting on the same tasks.
+func (c *CachedTaskRepository) Tasks(ctx context.Context, taskIDs []string) ([]*Task, error) {
+	now := time.Now()
+
+	tasks := make([]*Task, 0, len(taskIDs))
+	waiting := make([]*taskCall, 0)
+	fetching := make(map[string]*taskCall)
+
+	c.mu.Lock()
+	for _, id := range taskIDs {
+		if entry, ok := c.entries[id]; ok && now.Before(entry.expires) {
+			tasks = append(tasks, entry.task)
+			continue
+		}
+
+		if call, ok := c.inflight[id]; ok {
+			waiting = append(waiting, call)
+			continue
+		}
+
+		if _, ok := fetching[id]; ok {
+			continue
+		}
+
+		call := &taskCall{done: make(chan struct{})}
+		c.inflight[id] = call
+		fetching[id] = call
+		waiting = append(waiting, call)
+	}
+	c.mu.Unlock()
+
+	if len(fetching) > 0 {
+		go c.fetch(context.WithoutCancel(ctx), fetching)
+	}
+
+	for _, call := range waiting {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-call.done:
+		}
+
+		if call.err != nil {
+			return nil, call.err
+		}
+		if call.task != nil {
+			tasks = append(tasks, call.task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// fetch looks up the tasks of the in-flight calls, caching the result and releasing the lookups
+// waiting on them. The context must not be canceled by any one lookup.
+func (c *CachedTaskRepository) fetch(ctx context.Context, calls map[string]*taskCall) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	ids := make([]string, 0, len(calls))
+	for id := range calls {
+		ids = append(ids, id)
+	}
+
+	tasks, err := c.TaskRepository.Tasks(ctx, ids)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.sweep(now)
+
+	expires := now.Add(c.ttl)
+	for _, task := range tasks {
+		c.entries[task.ID] = cacheEntry{task: task, expires: expires}
+		if call, ok := calls[task.ID]; ok {
+			call.task = task
+		}
+	}
+
+	for id, call := range calls {
+		call.err = err
+		delete(c.inflight, id)
+		close(call.done)
+	}
+}
+
+// sweep removes the expired entries, at most once per TTL so that a busy cache isn't scanned on
+// every fetch. It must be called with the lock held.
+func (c *CachedTaskRepository) sweep(now time.Time) {
+	if now.Sub(c.swept) < c.ttl {
+		return
+	}
+
+	for id, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, id)
+		}
+	}
+
+	c.swept = now
+}
@@ -0,0 +1,281 @@
+package daybook
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Team is a group of users whose daybooks are rolled up into a digest for the team's channel.
+type Team struct {
+	Name    string
+	Channel string
+	Members []*User
+
+	// Timezone determines the day the digest is dated. UTC is used when it is nil.
+	Timezone *time.Location
+	// Crontab overrides the default schedule of the team's digest when set.
+	Crontab string
+}
+
+// Location returns the team's time zone, defaulting to UTC.
+func (t *Team) Location() *time.Location {
+	if t.Timezone == nil {
+		return time.UTC
+	}
+
+	return t.Timezone
+}
+
+// TeamDigest rolls up the daybooks of a team's members, for managers to see the whole team at once.
+type TeamDigest struct {
+	Team *Team
+	Day  time.Time
+
+	// Epics lists who is working on what within each epic, leaving out completed tasks.
+	Epics []*TeamEpic
+	// Other lists who is working on tasks that aren't within an epic, such as bugs.
+	Other []*MemberTasks
+	// Completed is the number of tasks the team completed today.
+	Completed int
+	// InReview lists the tasks in review sections that didn't move there since the member's previous
+	// daybook.
+	InReview []*MemberTasks
+
+	// Warnings are shown alongside the digest when it may be incomplete, such as when a member's
+	// daybook couldn't be generated.
+	Warnings []string
+}
+
+type TeamEpic struct {
+	*Task
+
+	Members []*MemberTasks
+}
+
+// MemberTasks are the tasks of one member of a team.
+type MemberTasks struct {
+	User  *User
+	Tasks []*Task
+}
+
+// MemberDaybook is a team member's daybook as it was stored by the daybook entry job, along with the
+// changes since the member's previous daybook.
+type MemberDaybook struct {
+	User    *User
+	Entry   *HistoryEntry
+	Changes *Changes
+}
+
+// SendTeamDigest rolls up the daybooks sent to the team's members today and sends the digest to the
+// notifier. Members whose daybook wasn't sent today are left out of the digest with a warning, rather
+// than failing it.
+func (s *Service) SendTeamDigest(ctx context.Context, team *Team) error {
+	color.White("Sending team digest for %s", team.Name)
+
+	digest, err := s.generateTeamDigest(ctx, team)
+	if err != nil {
+		return err
+	}
+
+	err = s.notifier.SendTeamDigest(ctx, digest)
+	if err != nil {
+		return fmt.Errorf("sending team digest: %w", err)
+	}
+
+	return nil
+}
+
+// generateTeamDigest reads the members' daybooks for today from the history, rather than generating
+// them again, so that the digest shows what the members were sent. Without a history the daybooks
+// are generated from Jira instead.
+func (s *Service) generateTeamDigest(ctx context.Context, team *Team) (*TeamDigest, error) {
+	if s.history == nil {
+		return s.generateTeamDigestFromJira(ctx, team), nil
+	}
+
+	daybooks := make([]*MemberDaybook, 0, len(team.Members))
+	warnings := make([]string, 0)
+
+	for _, member := range team.Members {
+		day := time.Now().In(member.Location()).Format(DayFormat)
+
+		entry, err := s.history.Daybook(ctx, member.SlackID, day)
+		if err != nil {
+			return nil, fmt.Errorf("getting @%s's daybook: %w", member.SlackHandle, err)
+		}
+		if entry == nil {
+			warnings = append(warnings, fmt.Sprintf("@%s's daybook for %s hasn't been sent", member.SlackHandle, day))
+			continue
+		}
+
+		previous, err := s.history.PreviousDaybook(ctx, member.SlackID, day)
+		if err != nil {
+			return nil, fmt.Errorf("getting @%s's previous daybook: %w", member.SlackHandle, err)
+		}
+
+		var changes *Changes
+		if previous != nil {
+			changes = DiffDaybooks(previous, entry, s.cfg.Statuses)
+		}
+
+		daybooks = append(daybooks, &MemberDaybook{User: member, Entry: entry, Changes: changes})
+	}
+
+	digest := NewTeamDigest(team, daybooks, s.cfg.Statuses)
+	digest.Warnings = append(digest.Warnings, warnings...)
+
+	return digest, nil
+}
+
+func (s *Service) generateTeamDigestFromJira(ctx context.Context, team *Team) *TeamDigest {
+	mu := sync.Mutex{}
+	byUser := make(map[*User]*Daybook)
+
+	result := s.forEachUser(ctx, "Generating daybook for team digest", team.Members, func(ctx context.Context, user *User) error {
+		db, err := s.generateDaybookEntry(ctx, user, time.Now().In(user.Location()))
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		byUser[user] = db
+		mu.Unlock()
+
+		return nil
+	})
+
+	// Keep the members in the configured order
+	daybooks := make([]*MemberDaybook, 0, len(byUser))
+	for _, member := range team.Members {
+		if db, ok := byUser[member]; ok {
+			daybooks = append(daybooks, &MemberDaybook{
+				User:    member,
+				Entry:   NewHistoryEntry(db, s.cfg.Statuses),
+				Changes: db.Changes,
+			})
+		}
+	}
+
+	digest := NewTeamDigest(team, daybooks, s.cfg.Statuses)
+
+	for _, r := range result.Failed() {
+		digest.Warnings = append(digest.Warnings, fmt.Sprintf("@%s's daybook couldn't be generated: %v", r.User.SlackHandle, r.Err))
+	}
+
+	return digest
+}
+
+// NewTeamDigest rolls up the members' daybooks. Only the tasks reported on their own are counted,
+// rather than the epics and parent stories shown for context.
+func NewTeamDigest(team *Team, daybooks []*MemberDaybook, statuses *StatusModel) *TeamDigest {
+	digest := &TeamDigest{
+		Team:     team,
+		Day:      time.Now().In(team.Location()),
+		Epics:    make([]*TeamEpic, 0),
+		Other:    make([]*MemberTasks, 0),
+		InReview: make([]*MemberTasks, 0),
+	}
+
+	epics := make(map[string]*TeamEpic)
+
+	for _, db := range daybooks {
+		// Find the epic each of the member's tasks is within
+		epicOf := epicsOf(db.Entry)
+
+		// Tasks that moved into their section since the previous daybook aren't stuck there
+		movedToday := make(map[string]bool)
+		if db.Changes != nil {
+			for _, change := range db.Changes.Tasks {
+				movedToday[change.ID] = change.To != nil
+			}
+		}
+
+		reported := reportedTasks(db.Entry, statuses)
+		ids := make([]string, 0, len(reported))
+		for id := range reported {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+
+		other := &MemberTasks{User: db.User}
+		inReview := &MemberTasks{User: db.User}
+		for _, id := range ids {
+			task := reported[id]
+			if task.done() {
+				digest.Completed++
+				continue
+			}
+
+			if task.section.Review && !movedToday[id] {
+				inReview.Tasks = append(inReview.Tasks, task.task)
+			}
+
+			epic, ok := epicOf[id]
+			if !ok {
+				other.Tasks = append(other.Tasks, task.task)
+				continue
+			}
+
+			if _, ok := epics[epic.ID]; !ok {
+				epics[epic.ID] = &TeamEpic{Task: epic}
+				digest.Epics = append(digest.Epics, epics[epic.ID])
+			}
+
+			teamEpic := epics[epic.ID]
+			i := slices.IndexFunc(teamEpic.Members, func(m *MemberTasks) bool { return m.User == db.User })
+			if i < 0 {
+				teamEpic.Members = append(teamEpic.Members, &MemberTasks{User: db.User})
+				i = len(teamEpic.Members) - 1
+			}
+			teamEpic.Members[i].Tasks = append(teamEpic.Members[i].Tasks, task.task)
+		}
+
+		if len(other.Tasks) > 0 {
+			digest.Other = append(digest.Other, other)
+		}
+		if len(inReview.Tasks) > 0 {
+			digest.InReview = append(digest.InReview, inReview)
+		}
+	}
+
+	slices.SortFunc(digest.Epics, func(a, b *TeamEpic) int {
+		return cmp.Compare(a.Title, b.Title)
+	})
+
+	return digest
+}
+
+// epicsOf returns the epic each task of the entry is within, keyed by task ID. A task's epic is its
+// nearest ancestor stored as an epic; tasks that aren't within one are left out.
+func epicsOf(entry *HistoryEntry) map[string]*Task {
+	epics := make(map[string]*Task)
+	parents := make(map[string]string)
+	for _, task := range entry.Tasks {
+		if task.Kind == TaskKindEpic {
+			epics[task.ID] = task.Task
+		}
+		if task.ParentTaskID != "" {
+			parents[task.ID] = task.ParentTaskID
+		}
+	}
+
+	epicOf := make(map[string]*Task)
+	for _, task := range entry.Tasks {
+		seen := map[string]bool{task.ID: true}
+		for id := parents[task.ID]; id != "" && !seen[id]; id = parents[id] {
+			if epic, ok := epics[id]; ok {
+				epicOf[task.ID] = epic
+				break
+			}
+			seen[id] = true
+		}
+	}
+
+	return epicOf
+}
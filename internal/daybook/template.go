@@ -0,0 +1,234 @@
+package daybook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// DaybookTemplate renders daybooks with a text/template, so that teams can customize the layout of
+// their daybooks. Templates are executed with TemplateData, and can use the helper functions of
+// TemplateFuncs.
+type DaybookTemplate struct {
+	tmpl     *template.Template
+	statuses *StatusModel
+}
+
+// ParseDaybookTemplate parses a daybook template. The statuses determine the sections the tasks are
+// grouped in, and DefaultStatusModel is used when nil.
+func ParseDaybookTemplate(name, text string, statuses *StatusModel) (*DaybookTemplate, error) {
+	if statuses == nil {
+		statuses = DefaultStatusModel()
+	}
+
+	tmpl, err := template.New(name).Funcs(TemplateFuncs(statuses)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing daybook template: %w", err)
+	}
+
+	return &DaybookTemplate{tmpl: tmpl, statuses: statuses}, nil
+}
+
+// Execute renders the daybook to w.
+func (t *DaybookTemplate) Execute(w io.Writer, db *Daybook) error {
+	err := t.tmpl.Execute(w, NewTemplateData(db, t.statuses))
+	if err != nil {
+		return fmt.Errorf("executing daybook template: %w", err)
+	}
+
+	return nil
+}
+
+// ExecuteSample renders a sample daybook with an entry of every kind to w, so that a template can be
+// checked before any daybook is sent with it.
+func (t *DaybookTemplate) ExecuteSample(w io.Writer) error {
+	return t.Execute(w, sampleDaybook(t.statuses))
+}
+
+// sampleDaybook returns a daybook with a project tree, a bug and a standalone task in each section of
+// the statuses, along with changes, a created task and a warning.
+func sampleDaybook(statuses *StatusModel) *Daybook {
+	link, _ := url.Parse("https://example.atlassian.net/browse/DAY-1")
+	user := &User{SlackHandle: "sample", SlackID: "U00000000"}
+
+	db := &Daybook{
+		Day:      time.Now(),
+		User:     user,
+		Projects: make(map[string][]*TaskNode),
+		Changes:  &Changes{Since: time.Now().AddDate(0, 0, -1).Format(DayFormat)},
+		Warnings: []string{"This is a sample daybook."},
+	}
+
+	for i, section := range statuses.Sections {
+		status := section.Name
+		if len(section.Statuses) > 0 {
+			status = section.Statuses[0]
+		}
+
+		task := func(kind, typ string, level int, parent string) *Task {
+			return &Task{
+				Type:           typ,
+				ID:             fmt.Sprintf("DAY-%d%s", i, kind),
+				Status:         status,
+				StatusCategory: section.Category,
+				Link:           link,
+				Title:          fmt.Sprintf("Sample %s in %s", strings.ToLower(typ), section.Label),
+				ParentTaskID:   parent,
+				Level:          level,
+			}
+		}
+
+		epic := task("E", "Epic", LevelEpic, "")
+		story := task("S", "Story", LevelStory, epic.ID)
+		subtask := task("T", "Subtask", LevelSubtask, story.ID)
+
+		db.Projects[section.Name] = []*TaskNode{
+			{Task: epic, Children: []*TaskNode{{Task: story, Children: []*TaskNode{{Task: subtask}}}}},
+		}
+		db.Bugs = append(db.Bugs, task("B", "Bug", LevelStory, ""))
+		db.StandaloneTasks = append(db.StandaloneTasks, task("A", "Task", LevelStory, ""))
+
+		db.Changes.Tasks = append(db.Changes.Tasks, &TaskChange{Task: story, Kind: ChangeStarted, To: section})
+	}
+
+	db.CreatedTasks = []*Task{{Type: "Task", ID: "DAY-C", Status: "To Do", Link: link, Title: "Sample created task"}}
+
+	return db
+}
+
+// TemplateData is what daybook templates are executed with: the daybook, along with its tasks
+// grouped by status section and its report.
+type TemplateData struct {
+	*Daybook
+
+	// Sections are the status sections with tasks, in the configured order.
+	Sections []*TemplateSection
+	// Report is the daybook laid out as it is shown without a template, with the same sections,
+	// items and warnings as every other output.
+	Report *Report
+}
+
+// TemplateSection is a status section of the daybook along with its tasks.
+type TemplateSection struct {
+	*StatusSection
+
+	// Entries are the section's bugs, project trees and standalone tasks, in that order. The tasks
+	// of a tree follow their parent.
+	Entries []*TemplateEntry
+}
+
+// TemplateEntry is a task of a section, along with its depth within its project tree.
+type TemplateEntry struct {
+	*Task
+
+	Depth int
+}
+
+// TemplateItem is an item of a report section, along with its depth below the section's top-level
+// items.
+type TemplateItem struct {
+	*ReportItem
+
+	Depth int
+}
+
+// NewTemplateData groups the daybook's tasks by the sections of the statuses.
+func NewTemplateData(db *Daybook, statuses *StatusModel) *TemplateData {
+	data := &TemplateData{
+		Daybook:  db,
+		Sections: make([]*TemplateSection, 0),
+		Report:   NewDaybookReport(db, statuses),
+	}
+
+	bugs := statuses.TasksBySection(db.Bugs)
+	standalones := statuses.TasksBySection(db.StandaloneTasks)
+
+	for _, status := range statuses.Sections {
+		section := &TemplateSection{StatusSection: status, Entries: make([]*TemplateEntry, 0)}
+
+		for _, bug := range bugs[status.Name] {
+			section.Entries = append(section.Entries, &TemplateEntry{Task: bug})
+		}
+
+		for _, root := range db.Projects[status.Name] {
+			root.Walk(func(node *TaskNode, depth int) {
+				section.Entries = append(section.Entries, &TemplateEntry{Task: node.Task, Depth: depth})
+			})
+		}
+
+		for _, task := range standalones[status.Name] {
+			section.Entries = append(section.Entries, &TemplateEntry{Task: task})
+		}
+
+		if len(section.Entries) > 0 {
+			data.Sections = append(data.Sections, section)
+		}
+	}
+
+	return data
+}
+
+// TemplateFuncs returns the helper functions available to daybook templates:
+//
+//   - statusLabel returns the label of the section a task is reported in
+//   - emoji returns the name of the emoji shown next to a task
+//   - link returns the URL of a task
+//   - indent returns four spaces for each level of depth
+//   - items returns report items followed by their descendants, each with its depth
+//   - json encodes a value as JSON, such as a string within a JSON template
+//   - white, green and yellow color text for the terminal
+func TemplateFuncs(statuses *StatusModel) template.FuncMap {
+	return template.FuncMap{
+		"statusLabel": func(task *Task) string {
+			section, ok := statuses.Section(task)
+			if !ok {
+				return task.Status
+			}
+
+			return section.Label
+		},
+		"emoji": func(task *Task) string {
+			return task.Emoji()
+		},
+		"link": func(task *Task) string {
+			if task.Link == nil {
+				return ""
+			}
+
+			return task.Link.String()
+		},
+		"indent": func(depth int) string {
+			return strings.Repeat(" ", depth*indentAmount)
+		},
+		"items": func(items []*ReportItem) []*TemplateItem {
+			flattened := make([]*TemplateItem, 0, len(items))
+			var walk func(items []*ReportItem, depth int)
+			walk = func(items []*ReportItem, depth int) {
+				for _, item := range items {
+					flattened = append(flattened, &TemplateItem{ReportItem: item, Depth: depth})
+					walk(item.Children, depth+1)
+				}
+			}
+			walk(items, 0)
+
+			return flattened
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return "", err
+			}
+
+			return string(b), nil
+		},
+		"white":  color.New(color.FgWhite).Sprint,
+		"green":  color.New(color.FgGreen).Sprint,
+		"yellow": color.New(color.FgYellow).Sprint,
+	}
+}
@@ -0,0 +1,157 @@
+package daybook
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// WeeklySummary rolls up a user's week: what they completed, grouped by epic, what's still in
+// progress, and how many of their tasks are in each status.
+type WeeklySummary struct {
+	User *User
+	// Week spans from the start of the user's week, on Monday, until the summary was generated.
+	Week Window
+
+	Completed  *TaskGroup
+	InProgress *TaskGroup
+	// StatusCounts counts the user's tasks by their Jira status, most common first.
+	StatusCounts []StatusCount
+
+	// Warnings are shown alongside the summary when it may be incomplete.
+	Warnings []string
+}
+
+// TaskGroup holds tasks organized into trees following the Jira issue hierarchy, along with the
+// tasks without a parent or children.
+type TaskGroup struct {
+	Projects []*TaskNode
+	Tasks    []*Task
+	// Count is the number of tasks in the group, not counting the epics and parent stories they're
+	// organized under.
+	Count int
+}
+
+type StatusCount struct {
+	Status string
+	Count  int
+}
+
+// WeekWindow returns the window from the start of the week of t, on Monday, in t's location, until t.
+func WeekWindow(t time.Time) Window {
+	start := DayWindow(t).Start
+	daysSinceMonday := (int(start.Weekday()) + 6) % 7
+
+	return Window{Start: start.AddDate(0, 0, -daysSinceMonday), End: t}
+}
+
+// SendWeeklySummaries sends the weekly summary of every user, several users at a time. A failure
+// for one user doesn't stop the others, and is reported in the result and recorded as a dead letter.
+func (s *Service) SendWeeklySummaries(ctx context.Context, users []*User) *BatchResult {
+	result := s.forEachUser(ctx, "Sending weekly summary", users, s.SendWeeklySummary)
+	s.recordDeadLetters(ctx, KindWeekly, result)
+
+	return result
+}
+
+// SendWeeklySummary computes the summary of the user's current week and sends it to the notifier.
+func (s *Service) SendWeeklySummary(ctx context.Context, user *User) error {
+	color.White("Sending weekly summary for @%s", user.SlackHandle)
+
+	summary, err := s.generateWeeklySummary(ctx, user)
+	if err != nil {
+		return fmt.Errorf("generating weekly summary: %w", err)
+	}
+
+	err = s.notifier.SendWeeklySummary(ctx, summary)
+	if err != nil {
+		return fmt.Errorf("sending weekly summary: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) generateWeeklySummary(ctx context.Context, user *User) (*WeeklySummary, error) {
+	week := WeekWindow(time.Now().In(user.Location()))
+	summary := &WeeklySummary{User: user, Week: week}
+
+	// Searching the week's window matches the tasks completed during the week, along with the
+	// ones still in progress
+	result, err := s.tasks.UserTasks(ctx, user, week)
+	if err != nil {
+		return nil, fmt.Errorf("getting user tasks: %w", err)
+	}
+
+	if result.Truncated() {
+		summary.Warnings = append(summary.Warnings, fmt.Sprintf("Only %d of %d assigned tasks could be fetched, so some work may be missing.", len(result.Tasks), result.Total))
+	}
+
+	tasks := s.cfg.Statuses.RecentTasks(result.Tasks, week)
+
+	completed := make([]*Task, 0)
+	inProgress := make([]*Task, 0)
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		if s.cfg.Statuses.Completed(task) {
+			completed = append(completed, task)
+		} else {
+			inProgress = append(inProgress, task)
+		}
+
+		counts[task.Status]++
+	}
+
+	summary.Completed, err = s.groupTasks(ctx, completed)
+	if err != nil {
+		return nil, fmt.Errorf("grouping completed tasks: %w", err)
+	}
+
+	summary.InProgress, err = s.groupTasks(ctx, inProgress)
+	if err != nil {
+		return nil, fmt.Errorf("grouping tasks in progress: %w", err)
+	}
+
+	for status, count := range counts {
+		summary.StatusCounts = append(summary.StatusCounts, StatusCount{Status: status, Count: count})
+	}
+
+	slices.SortFunc(summary.StatusCounts, func(a, b StatusCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Status, b.Status))
+	})
+
+	return summary, nil
+}
+
+// groupTasks organizes the tasks into trees, keeping the tasks without a parent or children, along
+// with bugs, alongside them.
+func (s *Service) groupTasks(ctx context.Context, tasks []*Task) (*TaskGroup, error) {
+	roots, err := s.organizeTasks(ctx, slices.DeleteFunc(slices.Clone(tasks), func(task *Task) bool {
+		return task.Type == "Bug"
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	group := &TaskGroup{Projects: make([]*TaskNode, 0), Tasks: make([]*Task, 0), Count: len(tasks)}
+	for _, root := range roots {
+		if len(root.Children) > 0 {
+			group.Projects = append(group.Projects, root)
+		}
+	}
+
+	for _, task := range tasks {
+		inProject := slices.ContainsFunc(group.Projects, func(root *TaskNode) bool {
+			return root.ContainsTask(task.ID)
+		})
+
+		if !inProject {
+			group.Tasks = append(group.Tasks, task)
+		}
+	}
+
+	return group, nil
+}
@@ -0,0 +1,213 @@
+package publish
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/zioyero/jira-daybot/internal/atomicfile"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// Format renders reports as documents of one kind, such as Markdown.
+type Format interface {
+	// Extension is the file extension of the documents, such as ".md".
+	Extension() string
+	// Render writes the report as a standalone document.
+	Render(w io.Writer, report *daybook.Report) error
+	// RenderAppended writes the report to be appended to a file of reports. First is set when the
+	// file is empty, so that the format can start the file.
+	RenderAppended(w io.Writer, report *daybook.Report, first bool) error
+}
+
+// FileNotifier writes daybooks, weekly summaries and team digests as documents, to be published to
+// a static site or attached to reports. Written to a directory, each user gets one file per day,
+// named after the day within a directory of their own, and re-sending a day replaces its file.
+// Written to a file, every document is appended to it.
+type FileNotifier struct {
+	dir      string
+	file     string
+	format   Format
+	statuses *daybook.StatusModel
+
+	// mu keeps the documents of users sent at once from interleaving in the file
+	mu sync.Mutex
+}
+
+// NewFileNotifier creates a notifier writing a document of the format per user and day to dir. The
+// statuses determine the sections of daybooks, and DefaultStatusModel is used when nil.
+func NewFileNotifier(dir string, format Format, statuses *daybook.StatusModel) *FileNotifier {
+	if statuses == nil {
+		statuses = daybook.DefaultStatusModel()
+	}
+
+	return &FileNotifier{dir: dir, format: format, statuses: statuses}
+}
+
+// NewAppendingFileNotifier creates a notifier appending every document of the format to the file at
+// path. The statuses determine the sections of daybooks, and DefaultStatusModel is used when nil.
+func NewAppendingFileNotifier(path string, format Format, statuses *daybook.StatusModel) *FileNotifier {
+	n := NewFileNotifier("", format, statuses)
+	n.file = path
+
+	return n
+}
+
+// SendDaybookEntry writes the daybook to <dir>/<slack handle>/<day>.
+func (n *FileNotifier) SendDaybookEntry(ctx context.Context, db *daybook.Daybook) error {
+	dir, err := userDir(db.User)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(n.dir, dir, db.Day.Format(daybook.DayFormat)+n.format.Extension())
+
+	return n.write(path, daybook.NewDaybookReport(db, n.statuses))
+}
+
+// SendDaybookDMReminder does nothing, since the documents are only published once the daybooks are
+// sent.
+func (n *FileNotifier) SendDaybookDMReminder(ctx context.Context, db *daybook.Daybook) error {
+	return nil
+}
+
+// SendWeeklySummary writes the summary to <dir>/<slack handle>/week-<first day of the week>.
+func (n *FileNotifier) SendWeeklySummary(ctx context.Context, summary *daybook.WeeklySummary) error {
+	dir, err := userDir(summary.User)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(n.dir, dir, "week-"+summary.Week.Start.Format(daybook.DayFormat)+n.format.Extension())
+
+	return n.write(path, daybook.NewWeeklyReport(summary))
+}
+
+// SendTeamDigest writes the digest to <dir>/teams/<team name>/<day>.
+func (n *FileNotifier) SendTeamDigest(ctx context.Context, digest *daybook.TeamDigest) error {
+	dir := slug(digest.Team.Name)
+	if dir == "" {
+		return fmt.Errorf("team %q has no name usable as a directory", digest.Team.Name)
+	}
+
+	path := filepath.Join(n.dir, "teams", dir, digest.Day.Format(daybook.DayFormat)+n.format.Extension())
+
+	return n.write(path, daybook.NewTeamDigestReport(digest))
+}
+
+// userDir returns the name of the user's directory: their slug handle, falling back to their Slack
+// ID. Handles come from the config and mustn't be able to point outside the output directory.
+func userDir(user *daybook.User) (string, error) {
+	dir := cmp.Or(slug(user.SlackHandle), slug(user.SlackID))
+	if dir == "" {
+		return "", fmt.Errorf("user %q has no handle or Slack ID usable as a directory", user.SlackHandle)
+	}
+
+	return dir, nil
+}
+
+func (n *FileNotifier) write(path string, report *daybook.Report) error {
+	if n.file != "" {
+		return n.append(report)
+	}
+
+	buf := bytes.Buffer{}
+	err := n.format.Render(&buf, report)
+	if err != nil {
+		return fmt.Errorf("rendering %s: %w", path, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("creating directory for %s: %w", path, err)
+	}
+
+	err = atomicfile.Write(path, buf.Bytes(), 0o644)
+	if err != nil {
+		return err
+	}
+
+	color.White("Wrote %s", path)
+
+	return nil
+}
+
+// append appends the report to the file, starting the file if it is empty.
+func (n *FileNotifier) append(report *daybook.Report) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", n.file, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", n.file, err)
+	}
+
+	buf := bytes.Buffer{}
+	err = n.format.RenderAppended(&buf, report, info.Size() == 0)
+	if err != nil {
+		return fmt.Errorf("rendering %s: %w", report.Title, err)
+	}
+
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", n.file, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", n.file, err)
+	}
+
+	color.White("Wrote %s to %s", heading(report), n.file)
+
+	return nil
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns a name into a file name, such as "platform-team" for "Platform Team".
+func slug(name string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// emojis maps the Slack emoji names used in reports to Unicode, since Slack's custom emoji, such as
+// jira-epic, aren't available outside of Slack. Emoji that aren't listed are left out.
+var emojis = map[string]string{
+	"jira-initiative":  "🎯",
+	"jira-epic":        "⚡",
+	"jira-story":       "📗",
+	"jira-task":        "☑️",
+	"jira-subtask":     "▫️",
+	"jira-bug":         "🐞",
+	"white_check_mark": "✅",
+	"construction":     "🚧",
+	"eyes":             "👀",
+	"test_tube":        "🧪",
+	"rocket":           "🚀",
+	"memo":             "📝",
+	"hourglass":        "⌛",
+	"warning":          "⚠️",
+}
+
+// heading returns the title of the report, along with the user it's about.
+func heading(report *daybook.Report) string {
+	if report.User == nil {
+		return report.Title
+	}
+
+	return fmt.Sprintf("@%s's %s", report.User.SlackHandle, report.Title)
+}
@@ -0,0 +1,50 @@
+package publish
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+//go:embed templates/report.html.tmpl
+var htmlTemplate string
+
+var htmlReport = template.Must(template.New("report.html.tmpl").Funcs(template.FuncMap{
+	"heading": heading,
+	"emoji": func(name string) string {
+		return emojis[name]
+	},
+}).Parse(htmlTemplate))
+
+// HTML renders reports as standalone HTML pages.
+type HTML struct{}
+
+func (HTML) Extension() string {
+	return ".html"
+}
+
+func (HTML) Render(w io.Writer, report *daybook.Report) error {
+	return htmlReport.Execute(w, report)
+}
+
+// RenderAppended starts the file with the page's head, then adds the report as an article. The
+// page's closing tags are optional in HTML, which keeps the file valid as reports are appended.
+func (HTML) RenderAppended(w io.Writer, report *daybook.Report, first bool) error {
+	if first {
+		err := htmlReport.ExecuteTemplate(w, "head", "Daybooks")
+		if err != nil {
+			return err
+		}
+	}
+
+	err := htmlReport.ExecuteTemplate(w, "article", report)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,368 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// JSONVersion is the version of the schema of the JSON documents. Fields may be added within a
+// version, but removing, renaming or changing the meaning of a field increments it.
+const JSONVersion = 1
+
+// Kinds of JSON documents.
+const (
+	JSONKindDaybook       = "daybook"
+	JSONKindWeeklySummary = "weekly_summary"
+	JSONKindTeamDigest    = "team_digest"
+)
+
+// JSONNotifier writes daybooks, weekly summaries and team digests as JSON documents for dashboards
+// and scripts, one document per line. Documents are written to stdout, or appended to a file.
+type JSONNotifier struct {
+	// mu keeps the documents of users sent at once from interleaving
+	mu   sync.Mutex
+	path string
+	// statuses determine the sections of daybooks
+	statuses *daybook.StatusModel
+}
+
+// NewJSONNotifier creates a notifier appending documents to the file at path, or writing them to
+// stdout when path is empty. The statuses determine the sections of daybooks, and
+// DefaultStatusModel is used when nil.
+func NewJSONNotifier(path string, statuses *daybook.StatusModel) *JSONNotifier {
+	if statuses == nil {
+		statuses = daybook.DefaultStatusModel()
+	}
+
+	return &JSONNotifier{path: path, statuses: statuses}
+}
+
+type jsonDaybook struct {
+	Version     int       `json:"version"`
+	Kind        string    `json:"kind"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	User *jsonUser `json:"user"`
+	// Day is the user's local day the daybook covers, formatted as YYYY-MM-DD
+	Day      string         `json:"day"`
+	Sections []*jsonSection `json:"sections"`
+	// Changes is null when there is no previous daybook to compare with
+	Changes      *jsonChanges `json:"changes"`
+	CreatedTasks []*jsonTask  `json:"created_tasks"`
+	Warnings     []string     `json:"warnings"`
+}
+
+type jsonUser struct {
+	SlackHandle string `json:"slack_handle"`
+	SlackID     string `json:"slack_id"`
+	AtlassianID string `json:"atlassian_id"`
+}
+
+// jsonSection is a status section of a daybook. Its tasks are the roots of the task trees reported
+// in the section, such as epics, along with bugs and standalone tasks.
+type jsonSection struct {
+	Name  string      `json:"name"`
+	Label string      `json:"label"`
+	Tasks []*jsonTask `json:"tasks"`
+}
+
+type jsonTask struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	Level          int    `json:"level"`
+	Title          string `json:"title"`
+	Link           string `json:"link"`
+	Status         string `json:"status"`
+	StatusCategory string `json:"status_category"`
+	ParentID       string `json:"parent_id"`
+	// StartedAt and CompletedAt are null when they aren't known
+	StartedAt   *time.Time  `json:"started_at"`
+	CompletedAt *time.Time  `json:"completed_at"`
+	Children    []*jsonTask `json:"children"`
+}
+
+type jsonChanges struct {
+	// Since is the day of the previous daybook, formatted as YYYY-MM-DD
+	Since string        `json:"since"`
+	Tasks []*jsonChange `json:"tasks"`
+}
+
+type jsonChange struct {
+	Kind string `json:"kind"`
+	// From and To are the names of the sections the task moved between, and are null for started
+	// and dropped tasks respectively
+	From *string   `json:"from"`
+	To   *string   `json:"to"`
+	Task *jsonTask `json:"task"`
+}
+
+func (n *JSONNotifier) SendDaybookEntry(ctx context.Context, db *daybook.Daybook) error {
+	doc := &jsonDaybook{
+		Version:      JSONVersion,
+		Kind:         JSONKindDaybook,
+		GeneratedAt:  time.Now(),
+		User:         newJSONUser(db.User),
+		Day:          db.Day.Format(daybook.DayFormat),
+		Sections:     make([]*jsonSection, 0),
+		CreatedTasks: newJSONTasks(db.CreatedTasks),
+		Warnings:     nonNil(db.Warnings),
+	}
+
+	bugs := n.statuses.TasksBySection(db.Bugs)
+	standalones := n.statuses.TasksBySection(db.StandaloneTasks)
+
+	for _, status := range n.statuses.Sections {
+		section := &jsonSection{Name: status.Name, Label: status.Label, Tasks: newJSONTasks(bugs[status.Name])}
+		for _, root := range db.Projects[status.Name] {
+			section.Tasks = append(section.Tasks, newJSONTree(root))
+		}
+		section.Tasks = append(section.Tasks, newJSONTasks(standalones[status.Name])...)
+
+		if len(section.Tasks) > 0 {
+			doc.Sections = append(doc.Sections, section)
+		}
+	}
+
+	if db.Changes != nil {
+		doc.Changes = &jsonChanges{Since: db.Changes.Since, Tasks: make([]*jsonChange, 0, len(db.Changes.Tasks))}
+		for _, change := range db.Changes.Tasks {
+			doc.Changes.Tasks = append(doc.Changes.Tasks, &jsonChange{
+				Kind: change.Kind,
+				From: sectionName(change.From),
+				To:   sectionName(change.To),
+				Task: newJSONTask(change.Task),
+			})
+		}
+	}
+
+	return n.write(doc)
+}
+
+// SendDaybookDMReminder does nothing, since the documents are only written once the daybooks are
+// sent.
+func (n *JSONNotifier) SendDaybookDMReminder(ctx context.Context, db *daybook.Daybook) error {
+	return nil
+}
+
+type jsonWeeklySummary struct {
+	Version     int       `json:"version"`
+	Kind        string    `json:"kind"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	User *jsonUser `json:"user"`
+	// WeekStart is the Monday the week starts on, formatted as YYYY-MM-DD
+	WeekStart    string             `json:"week_start"`
+	Completed    *jsonTaskGroup     `json:"completed"`
+	InProgress   *jsonTaskGroup     `json:"in_progress"`
+	StatusCounts []*jsonStatusCount `json:"status_counts"`
+	Warnings     []string           `json:"warnings"`
+}
+
+// jsonTaskGroup holds the roots of the group's task trees, along with its bugs and standalone tasks.
+type jsonTaskGroup struct {
+	// Count is the number of tasks in the group, not counting the epics and parent stories they're
+	// organized under
+	Count int         `json:"count"`
+	Tasks []*jsonTask `json:"tasks"`
+}
+
+type jsonStatusCount struct {
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+func (n *JSONNotifier) SendWeeklySummary(ctx context.Context, summary *daybook.WeeklySummary) error {
+	doc := &jsonWeeklySummary{
+		Version:      JSONVersion,
+		Kind:         JSONKindWeeklySummary,
+		GeneratedAt:  time.Now(),
+		User:         newJSONUser(summary.User),
+		WeekStart:    summary.Week.Start.Format(daybook.DayFormat),
+		Completed:    newJSONTaskGroup(summary.Completed),
+		InProgress:   newJSONTaskGroup(summary.InProgress),
+		StatusCounts: make([]*jsonStatusCount, 0, len(summary.StatusCounts)),
+		Warnings:     nonNil(summary.Warnings),
+	}
+
+	for _, count := range summary.StatusCounts {
+		doc.StatusCounts = append(doc.StatusCounts, &jsonStatusCount{Status: count.Status, Count: count.Count})
+	}
+
+	return n.write(doc)
+}
+
+type jsonTeamDigest struct {
+	Version     int       `json:"version"`
+	Kind        string    `json:"kind"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	Team *jsonTeam `json:"team"`
+	// Day is the team's local day the digest covers, formatted as YYYY-MM-DD
+	Day       string             `json:"day"`
+	Completed int                `json:"completed"`
+	Epics     []*jsonTeamEpic    `json:"epics"`
+	Other     []*jsonMemberTasks `json:"other"`
+	InReview  []*jsonMemberTasks `json:"in_review"`
+	Warnings  []string           `json:"warnings"`
+}
+
+type jsonTeam struct {
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+}
+
+type jsonTeamEpic struct {
+	Epic    *jsonTask          `json:"epic"`
+	Members []*jsonMemberTasks `json:"members"`
+}
+
+type jsonMemberTasks struct {
+	User  *jsonUser   `json:"user"`
+	Tasks []*jsonTask `json:"tasks"`
+}
+
+func (n *JSONNotifier) SendTeamDigest(ctx context.Context, digest *daybook.TeamDigest) error {
+	doc := &jsonTeamDigest{
+		Version:     JSONVersion,
+		Kind:        JSONKindTeamDigest,
+		GeneratedAt: time.Now(),
+		Team:        &jsonTeam{Name: digest.Team.Name, Channel: digest.Team.Channel},
+		Day:         digest.Day.Format(daybook.DayFormat),
+		Completed:   digest.Completed,
+		Epics:       make([]*jsonTeamEpic, 0, len(digest.Epics)),
+		Other:       newJSONMembers(digest.Other),
+		InReview:    newJSONMembers(digest.InReview),
+		Warnings:    nonNil(digest.Warnings),
+	}
+
+	for _, epic := range digest.Epics {
+		doc.Epics = append(doc.Epics, &jsonTeamEpic{Epic: newJSONTask(epic.Task), Members: newJSONMembers(epic.Members)})
+	}
+
+	return n.write(doc)
+}
+
+// write writes the document as a single line.
+func (n *JSONNotifier) write(doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encoding json document: %w", err)
+	}
+	data = append(data, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var w io.Writer = os.Stdout
+	if n.path != "" {
+		f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", n.path, err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("writing json document: %w", err)
+	}
+
+	return nil
+}
+
+func newJSONUser(user *daybook.User) *jsonUser {
+	return &jsonUser{SlackHandle: user.SlackHandle, SlackID: user.SlackID, AtlassianID: user.AtlassianID}
+}
+
+func newJSONTask(task *daybook.Task) *jsonTask {
+	t := &jsonTask{
+		ID:             task.ID,
+		Type:           task.Type,
+		Level:          task.Level,
+		Title:          task.Title,
+		Status:         task.Status,
+		StatusCategory: task.StatusCategory,
+		ParentID:       task.ParentTaskID,
+		StartedAt:      optionalTime(task.StartedAt),
+		CompletedAt:    optionalTime(task.CompletedAt),
+		Children:       make([]*jsonTask, 0),
+	}
+
+	if task.Link != nil {
+		t.Link = task.Link.String()
+	}
+
+	return t
+}
+
+func newJSONTasks(tasks []*daybook.Task) []*jsonTask {
+	tt := make([]*jsonTask, 0, len(tasks))
+	for _, task := range tasks {
+		tt = append(tt, newJSONTask(task))
+	}
+
+	return tt
+}
+
+// newJSONTree returns the task of the node, with the tasks of its descendants as its children.
+func newJSONTree(node *daybook.TaskNode) *jsonTask {
+	t := newJSONTask(node.Task)
+	for _, child := range node.Children {
+		t.Children = append(t.Children, newJSONTree(child))
+	}
+
+	return t
+}
+
+func newJSONTaskGroup(group *daybook.TaskGroup) *jsonTaskGroup {
+	g := &jsonTaskGroup{Count: group.Count, Tasks: make([]*jsonTask, 0)}
+	for _, root := range group.Projects {
+		g.Tasks = append(g.Tasks, newJSONTree(root))
+	}
+	g.Tasks = append(g.Tasks, newJSONTasks(group.Tasks)...)
+
+	return g
+}
+
+func newJSONMembers(members []*daybook.MemberTasks) []*jsonMemberTasks {
+	mm := make([]*jsonMemberTasks, 0, len(members))
+	for _, member := range members {
+		mm = append(mm, &jsonMemberTasks{User: newJSONUser(member.User), Tasks: newJSONTasks(member.Tasks)})
+	}
+
+	return mm
+}
+
+func sectionName(section *daybook.StatusSection) *string {
+	if section == nil {
+		return nil
+	}
+
+	return &section.Name
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// nonNil returns an empty slice for nil, so that empty lists are encoded as [] rather than null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
@@ -0,0 +1,112 @@
+package publish
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// Markdown renders reports as GitHub-flavoured Markdown.
+type Markdown struct{}
+
+func (Markdown) Extension() string {
+	return ".md"
+}
+
+func (m Markdown) Render(w io.Writer, report *daybook.Report) error {
+	sb := strings.Builder{}
+	sb.WriteString("# " + escapeMarkdown(heading(report)) + "\n")
+
+	for _, section := range report.Sections {
+		sb.WriteString("\n## " + withEmoji(section.Emoji, escapeMarkdown(section.Title)) + "\n")
+
+		if len(section.Items) > 0 {
+			sb.WriteString("\n")
+		}
+
+		for _, item := range section.Items {
+			m.writeItem(&sb, item, 0)
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		sb.WriteString("\n")
+	}
+
+	for _, warning := range report.Warnings {
+		sb.WriteString("> " + withEmoji("warning", escapeMarkdown(warning)) + "\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// RenderAppended separates the report from the previous one with a horizontal rule.
+func (m Markdown) RenderAppended(w io.Writer, report *daybook.Report, first bool) error {
+	if !first {
+		_, err := io.WriteString(w, "\n---\n\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.Render(w, report)
+}
+
+// writeItem writes the item as a list item, with its children nested below it.
+func (m Markdown) writeItem(sb *strings.Builder, item *daybook.ReportItem, depth int) {
+	content := strings.Builder{}
+
+	if item.Badge != "" {
+		content.WriteString("**" + escapeMarkdown(item.Badge) + ":** ")
+	}
+
+	if item.User != nil {
+		content.WriteString("@" + escapeMarkdown(item.User.SlackHandle) + ": ")
+	}
+
+	links := make([]string, 0, len(item.Links))
+	for _, link := range item.Links {
+		if link.URL == nil {
+			links = append(links, escapeMarkdown(link.Text))
+		} else {
+			links = append(links, "["+escapeMarkdown(link.Text)+"]("+markdownURL(link.URL)+")")
+		}
+	}
+	content.WriteString(strings.Join(links, ", "))
+
+	sb.WriteString(strings.Repeat("  ", depth) + "- " + withEmoji(item.Emoji, content.String()) + "\n")
+
+	for _, child := range item.Children {
+		m.writeItem(sb, child, depth+1)
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// escapeMarkdown escapes the characters of text that Markdown would otherwise interpret.
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var urlEscaper = strings.NewReplacer("<", "%3C", ">", "%3E", " ", "%20", "\n", "%0A")
+
+// markdownURL returns the URL as a link destination. Destinations in angle brackets may contain
+// parentheses, which would otherwise end the link, such as in a JQL query.
+func markdownURL(u *url.URL) string {
+	return "<" + urlEscaper.Replace(u.String()) + ">"
+}
+
+// withEmoji prefixes the text with the Unicode emoji for the Slack emoji name, if there is one.
+func withEmoji(name, text string) string {
+	if emoji, ok := emojis[name]; ok {
+		return emoji + " " + text
+	}
+
+	return text
+}
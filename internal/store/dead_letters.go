@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/zioyero/jira-daybot/internal/atomicfile"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// DeadLetterFile stores dead letters as a JSON array in a file, rewriting the whole file on every
+// change. Dead letters are rare, so the file stays small.
+type DeadLetterFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewDeadLetterFile(path string) *DeadLetterFile {
+	return &DeadLetterFile{path: path}
+}
+
+func (f *DeadLetterFile) AddDeadLetter(_ context.Context, letter *daybook.DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	letters, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	return f.write(append(letters, letter))
+}
+
+func (f *DeadLetterFile) DeadLetters(_ context.Context) ([]*daybook.DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.read()
+}
+
+// RemoveDeadLetter removes the dead letter matching the letter's kind, user and failure time.
+func (f *DeadLetterFile) RemoveDeadLetter(_ context.Context, letter *daybook.DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	letters, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]*daybook.DeadLetter, 0, len(letters))
+	for _, l := range letters {
+		if l.Kind == letter.Kind && l.SlackID == letter.SlackID && l.FailedAt.Equal(letter.FailedAt) {
+			continue
+		}
+
+		kept = append(kept, l)
+	}
+
+	return f.write(kept)
+}
+
+func (f *DeadLetterFile) read() ([]*daybook.DeadLetter, error) {
+	letters := make([]*daybook.DeadLetter, 0)
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return letters, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading dead letters: %w", err)
+	}
+
+	err = json.Unmarshal(data, &letters)
+	if err != nil {
+		return nil, fmt.Errorf("parsing dead letters %s: %w", f.path, err)
+	}
+
+	return letters, nil
+}
+
+// write replaces the file atomically, so a crash mid-write never loses the existing dead letters.
+func (f *DeadLetterFile) write(letters []*daybook.DeadLetter) error {
+	data, err := json.MarshalIndent(letters, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding dead letters: %w", err)
+	}
+
+	return atomicfile.Write(f.path, data, 0o600)
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zioyero/jira-daybot/internal/atomicfile"
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+// SentMessageFile stores the records of posted messages as a JSON array in a file. Records older than
+// the retention are pruned when the file is loaded, and dropped from the file on the next write.
+type SentMessageFile struct {
+	path      string
+	retention time.Duration
+	mu        sync.Mutex
+}
+
+// NewSentMessageFile creates a store in the file at path, keeping records for the retention. Records
+// are kept forever when the retention is zero.
+func NewSentMessageFile(path string, retention time.Duration) *SentMessageFile {
+	return &SentMessageFile{path: path, retention: retention}
+}
+
+func (f *SentMessageFile) SentMessage(_ context.Context, kind, owner, day, channel string) (*daybook.SentMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range messages {
+		if msg.Kind == kind && msg.Owner() == owner && msg.Day == day && msg.Channel == channel {
+			return msg, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f *SentMessageFile) RecordSentMessage(_ context.Context, msg *daybook.SentMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]*daybook.SentMessage, 0, len(messages)+1)
+	for _, m := range messages {
+		if m.Kind == msg.Kind && m.Owner() == msg.Owner() && m.Day == msg.Day && m.Channel == msg.Channel {
+			continue
+		}
+
+		kept = append(kept, m)
+	}
+
+	data, err := json.MarshalIndent(append(kept, msg), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding sent messages: %w", err)
+	}
+
+	return atomicfile.Write(f.path, data, 0o600)
+}
+
+func (f *SentMessageFile) read() ([]*daybook.SentMessage, error) {
+	messages := make([]*daybook.SentMessage, 0)
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return messages, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading sent messages: %w", err)
+	}
+
+	err = json.Unmarshal(data, &messages)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sent messages %s: %w", f.path, err)
+	}
+
+	// Records from before messages had kinds are all daybooks
+	for _, msg := range messages {
+		if msg.Kind == "" {
+			msg.Kind = daybook.SentKindDaybook
+		}
+	}
+
+	if f.retention > 0 {
+		cutoff := time.Now().Add(-f.retention)
+		messages = slices.DeleteFunc(messages, func(msg *daybook.SentMessage) bool {
+			return msg.SentAt.Before(cutoff)
+		})
+	}
+
+	return messages, nil
+}
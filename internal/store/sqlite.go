@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/zioyero/jira-daybot/internal/daybook"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS daybooks (
+	slack_id     TEXT NOT NULL,
+	day          TEXT NOT NULL,
+	slack_handle TEXT NOT NULL,
+	generated_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (slack_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS daybook_tasks (
+	slack_id        TEXT NOT NULL,
+	day             TEXT NOT NULL,
+	task_id         TEXT NOT NULL,
+	kind            TEXT NOT NULL,
+	section         TEXT NOT NULL,
+	type            TEXT NOT NULL,
+	status          TEXT NOT NULL,
+	status_category TEXT NOT NULL,
+	title           TEXT NOT NULL,
+	link            TEXT NOT NULL,
+	parent_id       TEXT NOT NULL,
+	level           INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (slack_id, day) REFERENCES daybooks (slack_id, day) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS daybook_tasks_daybook ON daybook_tasks (slack_id, day);
+CREATE INDEX IF NOT EXISTS daybook_tasks_task ON daybook_tasks (task_id);
+`
+
+// SQLite stores the daybook history in a SQLite database, one row per generated daybook and one
+// row per task reported in it, so that the history can be queried for reporting.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database at the path, creating it and its tables if they don't exist.
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", path, err)
+	}
+
+	_, err = db.Exec(schema)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating tables in %s: %w", path, err)
+	}
+
+	err = migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// migrate adds the columns that databases created by earlier versions are missing.
+func migrate(db *sql.DB) error {
+	var hasLevel bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('daybook_tasks') WHERE name = 'level'`).Scan(&hasLevel)
+	if err != nil {
+		return fmt.Errorf("reading daybook_tasks columns: %w", err)
+	}
+
+	if !hasLevel {
+		// Tasks stored before levels were only known by their kind, so initiatives stay epics
+		_, err = db.Exec(`ALTER TABLE daybook_tasks ADD COLUMN level INTEGER NOT NULL DEFAULT 0;
+			UPDATE daybook_tasks SET level = CASE kind WHEN 'epic' THEN 1 WHEN 'subtask' THEN -1 ELSE 0 END;`)
+		if err != nil {
+			return fmt.Errorf("adding level column: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// SaveDaybook stores the entry, replacing any daybook already stored for the user and day.
+func (s *SQLite) SaveDaybook(ctx context.Context, entry *daybook.HistoryEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM daybooks WHERE slack_id = ? AND day = ?`, entry.SlackID, entry.Day)
+	if err != nil {
+		return fmt.Errorf("deleting previous daybook: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO daybooks (slack_id, day, slack_handle, generated_at) VALUES (?, ?, ?, ?)`,
+		entry.SlackID, entry.Day, entry.SlackHandle, entry.GeneratedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("inserting daybook: %w", err)
+	}
+
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO daybook_tasks
+		(slack_id, day, task_id, kind, section, type, status, status_category, title, link, parent_id, level)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("preparing task insert: %w", err)
+	}
+	defer insert.Close()
+
+	for _, task := range entry.Tasks {
+		link := ""
+		if task.Link != nil {
+			link = task.Link.String()
+		}
+
+		_, err = insert.ExecContext(ctx, entry.SlackID, entry.Day, task.ID, task.Kind, task.Section,
+			task.Type, task.Status, task.StatusCategory, task.Title, link, task.ParentTaskID, task.Level)
+		if err != nil {
+			return fmt.Errorf("inserting task %s: %w", task.ID, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("committing daybook: %w", err)
+	}
+
+	return nil
+}
+
+// Daybook returns the daybook stored for the user and day, or nil if there isn't one.
+func (s *SQLite) Daybook(ctx context.Context, slackID, day string) (*daybook.HistoryEntry, error) {
+	entry := &daybook.HistoryEntry{SlackID: slackID, Day: day}
+
+	var generatedAt time.Time
+	err := s.db.QueryRowContext(ctx,
+		`SELECT slack_handle, generated_at FROM daybooks WHERE slack_id = ? AND day = ?`,
+		slackID, day).Scan(&entry.SlackHandle, &generatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying daybook: %w", err)
+	}
+	entry.GeneratedAt = generatedAt.Local()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT task_id, kind, section, type, status, status_category, title, link, parent_id, level
+		FROM daybook_tasks WHERE slack_id = ? AND day = ? ORDER BY rowid`, slackID, day)
+	if err != nil {
+		return nil, fmt.Errorf("querying daybook tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task := &daybook.HistoryTask{Task: &daybook.Task{}}
+
+		var link string
+		err = rows.Scan(&task.ID, &task.Kind, &task.Section, &task.Type, &task.Status, &task.StatusCategory,
+			&task.Title, &link, &task.ParentTaskID, &task.Level)
+		if err != nil {
+			return nil, fmt.Errorf("reading daybook task: %w", err)
+		}
+
+		if link != "" {
+			task.Link, err = url.Parse(link)
+			if err != nil {
+				return nil, fmt.Errorf("parsing link of task %s: %w", task.ID, err)
+			}
+		}
+
+		entry.Tasks = append(entry.Tasks, task)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("reading daybook tasks: %w", err)
+	}
+
+	return entry, nil
+}
+
+// PreviousDaybook returns the latest daybook stored for the user before the day, or nil if there
+// isn't one. Days are formatted with daybook.DayFormat, so they sort as strings.
+func (s *SQLite) PreviousDaybook(ctx context.Context, slackID, day string) (*daybook.HistoryEntry, error) {
+	var previous string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT day FROM daybooks WHERE slack_id = ? AND day < ? ORDER BY day DESC LIMIT 1`,
+		slackID, day).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying previous daybook: %w", err)
+	}
+
+	return s.Daybook(ctx, slackID, previous)
+}